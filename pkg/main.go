@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	// the timezones are embedded as the zoneinfo database might be missing on the host
+	_ "time/tzdata"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
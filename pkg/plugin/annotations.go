@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// annotationType is the query type of annotation queries. The columns with the following names
+// are converted for the annotations of Grafana. All other columns are returned unchanged
+const annotationType = "annotation"
+
+const (
+	annotationTimeColumn    = "time"
+	annotationTimeEndColumn = "timeEnd"
+	annotationTextColumn    = "text"
+	// annotationTagsColumn contains comma separated tags or a JSON array of tags
+	annotationTagsColumn = "tags"
+)
+
+// prepareAnnotationQuery sets the types of the annotation columns
+func prepareAnnotationQuery(queryConfig *queryConfigStruct) {
+	queryConfig.TimeColumns = append(
+		slices.Clone(queryConfig.TimeColumns), annotationTimeColumn, annotationTimeEndColumn,
+	)
+
+	columnTypes := maps.Clone(queryConfig.ColumnTypes)
+	if columnTypes == nil {
+		columnTypes = map[string]string{}
+	}
+	columnTypes[annotationTimeColumn] = "time"
+	columnTypes[annotationTimeEndColumn] = "time"
+	columnTypes[annotationTextColumn] = "string"
+	columnTypes[annotationTagsColumn] = "string"
+	queryConfig.ColumnTypes = columnTypes
+}
+
+// toAnnotationColumns checks the columns of an annotation query and converts the tags to JSON arrays
+func toAnnotationColumns(columns []*sqlColumn) error {
+	hasTimeColumn := false
+	for _, column := range columns {
+		switch column.Name {
+		case annotationTimeColumn:
+			hasTimeColumn = true
+		case annotationTagsColumn:
+			column.Type = "JSON"
+			column.JSONData = make([]*json.RawMessage, len(column.StringData))
+			for idx, value := range column.StringData {
+				if value == nil {
+					continue
+				}
+				tags, err := json.Marshal(parseAnnotationTags(*value))
+				if err != nil {
+					return err
+				}
+				rawTags := json.RawMessage(tags)
+				column.JSONData[idx] = &rawTags
+			}
+			column.StringData = nil
+		}
+	}
+
+	if !hasTimeColumn {
+		return fmt.Errorf("an annotation query needs a column named %s", annotationTimeColumn)
+	}
+	return nil
+}
+
+// parseAnnotationTags parses a JSON array or comma separated tags. Empty tags are removed
+func parseAnnotationTags(value string) []string {
+	value = strings.TrimSpace(value)
+
+	var jsonTags []interface{}
+	rawTags := []string{}
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &jsonTags) == nil {
+		for _, tag := range jsonTags {
+			if tag != nil {
+				rawTags = append(rawTags, fmt.Sprintf("%v", tag))
+			}
+		}
+	} else {
+		rawTags = strings.Split(value, ",")
+	}
+
+	tags := []string{}
+	for _, tag := range rawTags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestAnnotationQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE events(start INTEGER, end TEXT, description TEXT, labels TEXT, id INTEGER);
+		INSERT INTO events(start, end, description, labels, id)
+		VALUES	(1612325106, '2021-02-03T04:10:00Z', 'deploy', 'release, backend,', 1),
+				(1612328706, NULL, 42, '["alert", "critical"]', 2),
+				(1612332306, NULL, NULL, NULL, 3);
+	`)
+	defer cleanup()
+
+	queryText := `
+		SELECT start AS time, end AS timeEnd, description AS text, labels AS tags, id
+		FROM events ORDER BY start
+	`
+	dataQuery := getDataQuery(queryModel{QueryText: queryText})
+	dataQuery.QueryType = annotationType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 1 {
+		t.Fatalf("Expected one frame but got %d", len(response.Frames))
+	}
+
+	firstTags, secondTags := json.RawMessage(`["release","backend"]`), json.RawMessage(`["alert","critical"]`)
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("time", nil, []*time.Time{
+			unixTimePointer(1612325106), unixTimePointer(1612328706), unixTimePointer(1612332306),
+		}),
+		data.NewField("timeEnd", nil, []*time.Time{
+			timePointer(time.Date(2021, 2, 3, 4, 10, 0, 0, time.UTC)), nil, nil,
+		}),
+		data.NewField("text", nil, []*string{strPointer("deploy"), strPointer("42"), nil}),
+		data.NewField("tags", nil, []*json.RawMessage{&firstTags, &secondTags, nil}),
+		data.NewField("id", nil, []*int64{intPointer(1), intPointer(2), intPointer(3)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAnnotationQueryShouldRequireATimeColumn(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 'deploy' AS text"})
+	dataQuery.QueryType = annotationType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "needs a column named time") {
+		t.Errorf("Expected a missing time column error but got %v", response.Error)
+	}
+}
+
+func TestParseAnnotationTags(t *testing.T) {
+	for value, expected := range map[string][]string{
+		"":                     {},
+		"one":                  {"one"},
+		" one , two,,three ":   {"one", "two", "three"},
+		`["one", " two ", ""]`: {"one", "two"},
+		`[1, true, null]`:      {"1", "true"},
+		`[not json, two`:       {"[not json", "two"},
+	} {
+		if tags := parseAnnotationTags(value); !slices.Equal(tags, expected) {
+			t.Errorf("Expected %v for %s but got %v", expected, value, tags)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// the supported formats of BLOB values
+const (
+	blobFormatHex    = "hex"
+	blobFormatBase64 = "base64"
+	// blobFormatLength returns the number of bytes of the value (as integer)
+	blobFormatLength = "length"
+	// blobFormatUTF8 returns the value as text if it is valid UTF-8 and as hex otherwise
+	blobFormatUTF8 = "utf8"
+)
+
+var blobFormats = []string{blobFormatHex, blobFormatBase64, blobFormatLength, blobFormatUTF8}
+
+// defaultMaxBlobSize is the maximum number of bytes of BLOB values if the datasource has no limit
+const defaultMaxBlobSize = 1024 * 1024
+
+func (config pluginConfig) maxBlobSize() int {
+	if config.MaxBlobSize <= 0 {
+		return defaultMaxBlobSize
+	}
+	return config.MaxBlobSize
+}
+
+// blobFormat returns the format of the BLOB values of the column (UTF-8 by default)
+func (qc *queryConfigStruct) blobFormat(column string) (string, error) {
+	format, exists := qc.BlobFormats[column]
+	if !exists || format == "" {
+		return blobFormatUTF8, nil
+	}
+
+	format = strings.ToLower(format)
+	for _, supported := range blobFormats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported BLOB format '%s' for the column %s", format, column)
+}
+
+// formatBlob formats a BLOB value as text. Values above the maximum size (0 means no limit) are
+// truncated before formatting
+func formatBlob(value []byte, format string, maxSize int) (formatted string, truncated bool) {
+	validUTF8 := utf8.Valid(value)
+	if maxSize > 0 && len(value) > maxSize {
+		value = value[:maxSize]
+		truncated = true
+		// a truncated multi byte character is removed to keep the text valid
+		for validUTF8 && len(value) > 0 && !utf8.Valid(value) {
+			value = value[:len(value)-1]
+		}
+	}
+
+	switch format {
+	case blobFormatBase64:
+		return base64.StdEncoding.EncodeToString(value), truncated
+	case blobFormatUTF8:
+		if validUTF8 {
+			return string(value), truncated
+		}
+		return hex.EncodeToString(value), truncated
+	default:
+		return hex.EncodeToString(value), truncated
+	}
+}
+
+// blobNotices informs about truncated BLOB values
+func blobNotices(columns []*sqlColumn, maxSize int) []data.Notice {
+	notices := []data.Notice{}
+	for _, column := range columns {
+		if column.TruncatedValues > 0 {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text: fmt.Sprintf(
+					"%d BLOB values of the column %s were truncated to the limit of %d bytes",
+					column.TruncatedValues, column.Name, maxSize,
+				),
+			})
+		}
+	}
+	return notices
+}
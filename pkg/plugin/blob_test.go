@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestFormatBlob(t *testing.T) {
+	testCases := []struct {
+		name              string
+		value             []byte
+		format            string
+		maxSize           int
+		expected          string
+		expectedTruncated bool
+	}{
+		{"hex", []byte{0x00, 0xff}, blobFormatHex, 0, "00ff", false},
+		{"base64", []byte{0x00, 0xff}, blobFormatBase64, 0, "AP8=", false},
+		{"utf8 text", []byte("héllo"), blobFormatUTF8, 0, "héllo", false},
+		{"utf8 binary", []byte{0x89, 0x50, 0x4e, 0x47}, blobFormatUTF8, 0, "89504e47", false},
+		{"truncated hex", []byte{0x01, 0x02, 0x03}, blobFormatHex, 2, "0102", true},
+		{"truncated multi byte character", []byte("hé"), blobFormatUTF8, 2, "h", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			formatted, truncated := formatBlob(tc.value, tc.format, tc.maxSize)
+			if formatted != tc.expected || truncated != tc.expectedTruncated {
+				t.Errorf(
+					"Expected %q (truncated: %t) but got %q (truncated: %t)",
+					tc.expected, tc.expectedTruncated, formatted, truncated,
+				)
+			}
+		})
+	}
+}
+
+func TestBlobColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE files(name TEXT, content BLOB, preview BLOB, size BLOB);
+		INSERT INTO files VALUES
+			('image', X'89504E470D0A', X'89504E470D0A', X'89504E470D0A'),
+			('text', CAST('hello' AS BLOB), CAST('hello' AS BLOB), NULL);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT content, preview, size FROM files"
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   queryText,
+		BlobFormats: map[string]string{"preview": "base64", "size": "length"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, MaxBlobSize: 4})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("content", nil, []*string{strPointer("89504e47"), strPointer("hell")}),
+		data.NewField("preview", nil, []*string{strPointer("iVBORw=="), strPointer("aGVsbA==")}),
+		data.NewField("size", nil, []*int64{intPointer(6), nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: queryText,
+		Notices: []data.Notice{
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "2 BLOB values of the column content were truncated to the limit of 4 bytes",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "2 BLOB values of the column preview were truncated to the limit of 4 bytes",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUnsupportedBlobFormat(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   "SELECT X'00' AS content",
+		BlobFormats: map[string]string{"content": "png"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "unsupported BLOB format") {
+		t.Errorf("Expected an error about the BLOB format but got %v", response.Error)
+	}
+}
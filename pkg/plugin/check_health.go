@@ -11,7 +11,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
-func checkDB(pathPrefix string, path string, options string) error {
+func checkDB(db *sql.DB, pathPrefix string, path string, ctx context.Context) error {
 	if IsPathBlocked(path) {
 		return fmt.Errorf("path contains blocked term from GF_PLUGIN_BLOCK_LIST")
 	}
@@ -27,12 +27,7 @@ func checkDB(pathPrefix string, path string, options string) error {
 		}
 	}
 
-	db, err := sql.Open("sqlite", pathPrefix+path+"?"+options)
-	if err != nil {
-		return fmt.Errorf("error opening %s%s: %v", pathPrefix, path, err)
-	}
-
-	_, err = db.Exec("pragma schema_version;")
+	_, err := db.ExecContext(ctx, "pragma schema_version;")
 	if err != nil {
 		errMsg := fmt.Sprintf("error checking for valid SQLite file: %v", err)
 		if strings.Contains(err.Error(), "readonly") {
@@ -43,11 +38,6 @@ func checkDB(pathPrefix string, path string, options string) error {
 		return errors.New(errMsg)
 	}
 
-	err = db.Close()
-	if err != nil {
-		return fmt.Errorf("error closing database file: %v", err)
-	}
-
 	return nil
 }
 
@@ -58,7 +48,7 @@ func checkDB(pathPrefix string, path string, options string) error {
 func (ds *sqliteDatasource) CheckHealth(ctx context.Context, _ *backend.CheckHealthRequest) (
 	*backend.CheckHealthResult, error,
 ) {
-	err := checkDB(ds.pluginConfig.PathPrefix, ds.pluginConfig.Path, ds.pluginConfig.PathOptions)
+	err := checkDB(ds.db, ds.pluginConfig.PathPrefix, ds.pluginConfig.Path, ctx)
 	if err != nil {
 		return &backend.CheckHealthResult{
 			Status:  backend.HealthStatusError,
@@ -75,6 +65,13 @@ func (ds *sqliteDatasource) CheckHealth(ctx context.Context, _ *backend.CheckHea
 		}
 	}
 
+	if _, err := ds.pluginConfig.location(); err != nil {
+		return &backend.CheckHealthResult{
+			Status:  backend.HealthStatusError,
+			Message: err.Error(),
+		}, nil
+	}
+
 	return &backend.CheckHealthResult{
 		Status:  backend.HealthStatusOk,
 		Message: "Data source is working",
@@ -23,7 +23,7 @@ func TestCheckHealthShouldPassForADB(t *testing.T) {
 	_, _ = db.Exec("CREATE TABLE test(id int);")
 	_ = db.Close()
 
-	ds := sqliteDatasource{pluginConfig{Path: dbPath, PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: dbPath, PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -42,7 +42,7 @@ func TestCheckHealthShouldFailForANotExistingFile(t *testing.T) {
 	defer func() { _ = os.RemoveAll(dir) }()
 	notExistingDbPath := filepath.Join(dir, "my.db")
 
-	ds := sqliteDatasource{pluginConfig{Path: notExistingDbPath, PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: notExistingDbPath, PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -65,7 +65,7 @@ func TestCheckHealthShouldFailForAFolder(t *testing.T) {
 	dir, _ := os.MkdirTemp("", "test-check-db")
 	defer func() { _ = os.RemoveAll(dir) }()
 
-	ds := sqliteDatasource{pluginConfig{Path: dir, PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: dir, PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -86,7 +86,7 @@ func TestCheckHealthShouldFailOnTextFile(t *testing.T) {
 	_, _ = f.WriteString("not a sqlite db")
 	_ = f.Close()
 
-	ds := sqliteDatasource{pluginConfig{Path: f.Name(), PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: f.Name(), PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -104,7 +104,7 @@ func TestCheckHealthShouldPassForAnEmptyFile(t *testing.T) {
 	f, _ := os.CreateTemp("", "test-check-db")
 	defer func() { _ = syscall.Unlink(f.Name()) }()
 
-	ds := sqliteDatasource{pluginConfig{Path: f.Name(), PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: f.Name(), PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -128,7 +128,7 @@ func TestCheckHealthShouldFailWhenPathIsBlocked(t *testing.T) {
 	}()
 	_ = os.Setenv("GF_PLUGIN_BLOCK_LIST", "secret")
 
-	ds := sqliteDatasource{pluginConfig{Path: dbPath, PathPrefix: "file:"}}
+	ds := getDatasource(t, pluginConfig{Path: dbPath, PathPrefix: "file:"})
 	result, err := ds.CheckHealth(ctx, nil)
 	if err != nil {
 		t.Errorf("Unexpected error - %s", err)
@@ -151,7 +151,7 @@ func TestCheckHealth_AttachLimitAboveZero_Disallowed(t *testing.T) {
 	_ = db.Close()
 
 	attachLimit := int64(1)
-	ds := sqliteDatasource{pluginConfig{Path: dbPath, PathPrefix: "file:", AttachLimit: &attachLimit}}
+	ds := getDatasource(t, pluginConfig{Path: dbPath, PathPrefix: "file:", AttachLimit: &attachLimit})
 
 	// Ensure env variable is not set
 	originalValue := os.Getenv("GF_PLUGIN_UNSAFE_ALLOW_ATTACH_LIMIT_ABOVE_ZERO")
@@ -185,7 +185,7 @@ func TestCheckHealth_AttachLimitAboveZero_Allowed(t *testing.T) {
 	_ = db.Close()
 
 	attachLimit := int64(1)
-	ds := sqliteDatasource{pluginConfig{Path: dbPath, PathPrefix: "file:", AttachLimit: &attachLimit}}
+	ds := getDatasource(t, pluginConfig{Path: dbPath, PathPrefix: "file:", AttachLimit: &attachLimit})
 
 	// Set env variable to allow attach limit above zero
 	originalValue := os.Getenv("GF_PLUGIN_UNSAFE_ALLOW_ATTACH_LIMIT_ABOVE_ZERO")
@@ -206,3 +206,26 @@ func TestCheckHealth_AttachLimitAboveZero_Allowed(t *testing.T) {
 		t.Errorf("Expected HealthStatusOk, but got - %s", result.Status)
 	}
 }
+
+func TestCheckHealthShouldFailForAnUnknownTimezone(t *testing.T) {
+	dir, _ := os.MkdirTemp("", "test-check-db")
+	defer func() { _ = os.RemoveAll(dir) }()
+	dbPath := filepath.Join(dir, "my.db")
+
+	db, _ := sql.Open("sqlite", dbPath)
+	_, _ = db.Exec("CREATE TABLE test(id int);")
+	_ = db.Close()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath, PathPrefix: "file:", Timezone: "Mars/Olympus"})
+	result, err := ds.CheckHealth(ctx, nil)
+	if err != nil {
+		t.Errorf("Unexpected error - %s", err)
+	}
+
+	if result.Status != backend.HealthStatusError {
+		t.Errorf("Expected HealthStatusError, but got - %s", result.Status)
+	}
+	if !strings.Contains(result.Message, "unsupported timezone 'Mars/Olympus'") {
+		t.Errorf("Unexpected error message: %s", result.Message)
+	}
+}
@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"modernc.org/sqlite"
+)
+
+// https://www.sqlite.org/c3ref/c_limit_attached.html#sqlitelimitattached
+// #define SQLITE_LIMIT_ATTACHED                  7
+const sqliteLimitAttached = 7
+
+// pragmaStatementRegex matches PRAGMA statements, which can change the settings of a connection
+// beyond the query. Other state (attached databases, temporary tables and transactions) is
+// reported by SQLite itself
+var pragmaStatementRegex = regexp.MustCompile(`(?im)(?:^|;)\s*pragma\b`)
+
+// connector opens the connections of the pool of a datasource. Every datasource has its own
+// driver, so that the connection hook only sets up the connections of the datasource
+type connector struct {
+	driver *sqlite.Driver
+	dsn    string
+}
+
+func (c connector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// newConnector creates the connector of the datasource. Read-only datasources enforce the
+// query_only mode on every new connection
+func newConnector(config pluginConfig) connector {
+	sqliteDriver := &sqlite.Driver{}
+	sqliteDriver.RegisterConnectionHook(func(conn sqlite.ExecQuerierContext, _ string) error {
+		if !config.QueryOnly {
+			return nil
+		}
+		_, err := conn.ExecContext(context.Background(), "PRAGMA query_only = 1", nil)
+		return err
+	})
+
+	return connector{driver: sqliteDriver, dsn: dataSourceName(config)}
+}
+
+// dataSourceName returns the DSN of the database. The driver parses the values of DATE, DATETIME
+// and TIMESTAMP columns, so it needs the timezone of time strings without an offset
+func dataSourceName(config pluginConfig) string {
+	options := config.PathOptions
+	if _, err := config.location(); err == nil && config.Timezone != "" &&
+		!strings.Contains(options, "_timezone=") {
+		timezoneOption := "_timezone=" + url.QueryEscape(config.Timezone)
+		if options == "" {
+			options = timezoneOption
+		} else {
+			options = options + "&" + timezoneOption
+		}
+	}
+	return config.PathPrefix + config.Path + "?" + options
+}
+
+// queryConn takes a connection from the pool and applies the attach limit of the datasource.
+// SQLite only allows to set limits through its C API, which the driver exposes for connections
+// of the pool (but not to the connection hook)
+func queryConn(config pluginConfig, db *sql.DB, ctx context.Context) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		log.DefaultLogger.Error("Could not get connection", "err", err)
+		return nil, err
+	}
+
+	if config.AttachLimit != nil && os.Getenv("GF_PLUGIN_UNSAFE_ALLOW_ATTACH_LIMIT_ABOVE_ZERO") == "true" {
+		_, err = sqlite.Limit(conn, sqliteLimitAttached, int(*config.AttachLimit))
+	} else {
+		_, err = sqlite.Limit(conn, sqliteLimitAttached, 0)
+	}
+	if err != nil {
+		log.DefaultLogger.Error("Could not set attach limit", "err", err)
+		releaseQueryConn(conn, "")
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// releaseQueryConn returns the connection to the pool. Connections, whose state might have been
+// changed by the query, are closed instead. The next query then gets a newly set up connection
+func releaseQueryConn(conn *sql.Conn, query string) {
+	if pragmaStatementRegex.MatchString(query) || inTransaction(conn) || hasOtherDatabases(conn) {
+		log.DefaultLogger.Debug("Discarding connection after a statement changing its state")
+		// the pool closes connections, which report to be bad
+		_ = conn.Raw(func(_ any) error { return driver.ErrBadConn })
+		return
+	}
+
+	if err := conn.Close(); err != nil {
+		log.DefaultLogger.Error("Error closing connection", "err", err)
+	}
+}
+
+// inTransaction returns whether the query left a transaction open (e.g. with BEGIN), which would
+// keep the locks of the connection while it is idle in the pool. The driver does not expose
+// sqlite3_get_autocommit, but a ROLLBACK only succeeds within a transaction (and ends it)
+func inTransaction(conn *sql.Conn) bool {
+	err := conn.Raw(func(driverConn any) error {
+		execer, ok := driverConn.(driver.ExecerContext)
+		if !ok {
+			return fmt.Errorf("unexpected driverConn type: %T", driverConn)
+		}
+		_, err := execer.ExecContext(context.Background(), "ROLLBACK", nil)
+		return err
+	})
+	if err != nil && strings.Contains(err.Error(), "no transaction is active") {
+		return false
+	}
+	if err != nil {
+		log.DefaultLogger.Error("Could not check the transaction of the connection", "err", err)
+	}
+	return true
+}
+
+// hasOtherDatabases returns whether the connection has databases besides the main one, i.e.
+// attached databases or the temporary database (which exists after a temporary table was created)
+func hasOtherDatabases(conn *sql.Conn) bool {
+	var count int64
+	err := conn.Raw(func(driverConn any) error {
+		queryer, ok := driverConn.(driver.QueryerContext)
+		if !ok {
+			return fmt.Errorf("unexpected driverConn type: %T", driverConn)
+		}
+		rows, err := queryer.QueryContext(
+			context.Background(), "SELECT count(*) FROM pragma_database_list", nil,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		values := make([]driver.Value, 1)
+		if err := rows.Next(values); err != nil {
+			return err
+		}
+		count, _ = values[0].(int64)
+		return nil
+	})
+	if err != nil {
+		log.DefaultLogger.Error("Could not list the databases of the connection", "err", err)
+		return true
+	}
+	return count != 1
+}
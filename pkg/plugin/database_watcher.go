@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// databaseWatcher notifies about new data in the database file. It watches the directory of the
+// database for modifications of the database and its WAL file. As not every modification
+// contains new data (e.g. a checkpoint of the WAL file) the changes are confirmed with
+// PRAGMA data_version
+type databaseWatcher struct {
+	watcher *fsnotify.Watcher
+	// files are the paths of the database and its WAL file
+	files map[string]bool
+
+	// db is a separate pool with a single connection, so that watching the database does not
+	// take a connection from the pool of the queries
+	db *sql.DB
+	// conn is pinned as the data version is only comparable for one connection
+	conn        *sql.Conn
+	dataVersion int64
+}
+
+// watcherBusyTimeout is the time (in ms) to wait for a writer to finish the commit, which
+// triggered the file modification, before reading the data version
+const watcherBusyTimeout = 5000
+
+// newDatabaseWatcher creates a watcher for the database file. An error is returned for
+// databases without a regular file (e.g. in-memory databases)
+func newDatabaseWatcher(config pluginConfig, ctx context.Context) (*databaseWatcher, error) {
+	path, err := filepath.Abs(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("the database path is not a regular file: %s", path)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// the directory is watched because the WAL file is created and removed by SQLite
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	db, err := openDB(config)
+	if err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	dbWatcher := &databaseWatcher{
+		watcher: watcher,
+		files:   map[string]bool{path: true, path + "-wal": true},
+		db:      db,
+	}
+	dbWatcher.conn, err = db.Conn(ctx)
+	if err != nil {
+		_ = watcher.Close()
+		_ = db.Close()
+		return nil, err
+	}
+	_, err = dbWatcher.conn.ExecContext(ctx, fmt.Sprintf("PRAGMA busy_timeout = %d", watcherBusyTimeout))
+	if err == nil {
+		dbWatcher.dataVersion, err = dbWatcher.readDataVersion(ctx)
+	}
+	if err != nil {
+		dbWatcher.Close()
+		return nil, err
+	}
+
+	return dbWatcher, nil
+}
+
+func (w *databaseWatcher) readDataVersion(ctx context.Context) (int64, error) {
+	var dataVersion int64
+	err := w.conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&dataVersion)
+	return dataVersion, err
+}
+
+// waitForChange blocks until new data was committed to the database or the context is done
+func (w *databaseWatcher) waitForChange(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return fmt.Errorf("the database watcher was closed")
+			}
+			log.DefaultLogger.Warn("Received error from the database watcher", "err", err)
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return fmt.Errorf("the database watcher was closed")
+			}
+			if !w.files[event.Name] || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			dataVersion, err := w.readDataVersion(ctx)
+			if err != nil {
+				return err
+			}
+			if dataVersion != w.dataVersion {
+				w.dataVersion = dataVersion
+				return nil
+			}
+			log.DefaultLogger.Debug("Ignored database modification without new data", "file", event.Name)
+		}
+	}
+}
+
+// Close stops watching the database and closes its connection
+func (w *databaseWatcher) Close() {
+	if err := w.watcher.Close(); err != nil {
+		log.DefaultLogger.Error("Error closing database watcher", "err", err)
+	}
+	if err := w.conn.Close(); err != nil {
+		log.DefaultLogger.Error("Error closing connection", "err", err)
+	}
+	if err := w.db.Close(); err != nil {
+		log.DefaultLogger.Error("Error closing database", "err", err)
+	}
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDatabaseWatcherShouldNotifyAboutNewData(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`CREATE TABLE test(id INTEGER);`)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	watcher, err := newDatabaseWatcher(ds.pluginConfig, context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+	defer watcher.Close()
+
+	var inserted atomic.Bool
+	go func() {
+		// a modification of the watched files without new data must not notify
+		_ = os.WriteFile(dbPath+"-wal", []byte{}, 0600)
+		time.Sleep(200 * time.Millisecond)
+
+		inserted.Store(true)
+		insertRows(t, dbPath, "INSERT INTO test(id) VALUES (1)")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = watcher.waitForChange(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+	if !inserted.Load() {
+		t.Error("Expected to be notified only after new data was inserted")
+	}
+}
+
+func TestDatabaseWatcherShouldRequireADatabaseFile(t *testing.T) {
+	ds := getDatasource(t, pluginConfig{Path: ":memory:"})
+
+	_, err := newDatabaseWatcher(ds.pluginConfig, context.Background())
+	if err == nil {
+		t.Error("Expected error for an in-memory database but got nothing")
+	}
+}
@@ -1,32 +1,206 @@
 package plugin
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 )
 
+// the supported ways to fill gaps in the data. See parseGapFillArgument for the syntax
+const (
+	fillModeNull     = "null"
+	fillModePrevious = "previous"
+	fillModeLinear   = "linear"
+	fillModeValue    = "value"
+)
+
+// fillRangeFull is the gap filling argument to fill the whole time range of the query
+const fillRangeFull = "range"
+
+// gapFillValue holds the constant used to fill gaps (fillModeValue) for each column type.
+// A nil value means the constant does not fit the column type and NULL is used instead
+type gapFillValue struct {
+	Int    *int64
+	Float  *float64
+	String *string
+}
+
 func fillGaps(columns []*sqlColumn, queryConfig *queryConfigStruct) error {
 	if queryConfig.FillValuesTimeColumnIndex == -1 {
 		return fmt.Errorf("no time column found to use for gap filling")
 	}
 
+	// time series in the long format contain multiple series, which are identified by their
+	// string columns (the labels after converting to the wide format)
+	labelColumns := []int{}
+	if !queryConfig.isTableType() {
+		for idx, column := range columns {
+			if column.Type == "STRING" {
+				labelColumns = append(labelColumns, idx)
+			}
+		}
+	}
+	if len(labelColumns) == 0 {
+		return fillSeriesGaps(columns, queryConfig)
+	}
+
+	series, seriesLabels, err := splitSeries(
+		columns, labelColumns, queryConfig.FillValuesTimeColumnIndex,
+	)
+	if err != nil {
+		return err
+	}
+
+	for seriesIdx, seriesColumns := range series {
+		err := fillSeriesGaps(seriesColumns, queryConfig)
+		if err != nil {
+			return err
+		}
+
+		// the labels of the added rows are the labels of the series regardless of the fill mode.
+		// Gaps can also be added before the first row of the series (e.g. for the full range)
+		for labelNumber, labelIdx := range labelColumns {
+			label := seriesLabels[seriesIdx][labelNumber]
+			for row := range seriesColumns[labelIdx].StringData {
+				seriesColumns[labelIdx].StringData[row] = label
+			}
+		}
+	}
+
+	return mergeSeries(columns, series, queryConfig.FillValuesTimeColumnIndex)
+}
+
+// splitSeries splits the columns into one set of columns per series. A series consists of all
+// rows with the same values in the label columns. The series are ordered by their first row.
+// The labels of each series are returned in the order of the label columns
+func splitSeries(
+	columns []*sqlColumn, labelColumns []int, timeColumnIndex int,
+) ([][]*sqlColumn, [][]*string, error) {
+	rowCount := len(columns[timeColumnIndex].TimeData)
+	seriesIndexes := map[string]int{}
+	series := [][]*sqlColumn{}
+	seriesLabels := [][]*string{}
+
+	for row := 0; row < rowCount; row++ {
+		if columns[timeColumnIndex].TimeData[row] == nil {
+			return nil, nil, fmt.Errorf("received NULL value in time column")
+		}
+
+		key := seriesKey(columns, labelColumns, row)
+		seriesIdx, exists := seriesIndexes[key]
+		if !exists {
+			seriesIdx = len(series)
+			seriesIndexes[key] = seriesIdx
+
+			seriesColumns := make([]*sqlColumn, len(columns))
+			for idx, column := range columns {
+				seriesColumns[idx] = &sqlColumn{Name: column.Name, Type: column.Type}
+			}
+			series = append(series, seriesColumns)
+
+			labels := make([]*string, len(labelColumns))
+			for labelNumber, labelIdx := range labelColumns {
+				labels[labelNumber] = columns[labelIdx].StringData[row]
+			}
+			seriesLabels = append(seriesLabels, labels)
+		}
+
+		err := addValueToColumns(row, series[seriesIdx], columns, rowCount)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return series, seriesLabels, nil
+}
+
+// seriesKey identifies the series of a row by the values of its label columns
+func seriesKey(columns []*sqlColumn, labelColumns []int, row int) string {
+	var key strings.Builder
+	for _, labelIdx := range labelColumns {
+		value := columns[labelIdx].StringData[row]
+		if value == nil {
+			key.WriteString("NULL,")
+		} else {
+			key.WriteString(strconv.Quote(*value) + ",")
+		}
+	}
+	return key.String()
+}
+
+// mergeSeries combines the rows of all series into the columns ordered by time, which is
+// required for the conversion to the wide format. Rows with the same time keep the order of
+// their series
+func mergeSeries(columns []*sqlColumn, series [][]*sqlColumn, timeColumnIndex int) error {
+	type seriesRow struct {
+		series int
+		row    int
+	}
+
+	rows := []seriesRow{}
+	for seriesIdx, seriesColumns := range series {
+		for row := range seriesColumns[timeColumnIndex].TimeData {
+			rows = append(rows, seriesRow{series: seriesIdx, row: row})
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b seriesRow) int {
+		return series[a.series][timeColumnIndex].TimeData[a.row].Compare(
+			*series[b.series][timeColumnIndex].TimeData[b.row],
+		)
+	})
+
+	mergedColumns := make([]*sqlColumn, len(columns))
+	for idx, column := range columns {
+		mergedColumns[idx] = &sqlColumn{Name: column.Name, Type: column.Type}
+	}
+	for _, row := range rows {
+		seriesColumns := series[row.series]
+		err := addValueToColumns(
+			row.row, mergedColumns, seriesColumns, len(seriesColumns[timeColumnIndex].TimeData),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for idx := range columns {
+		columns[idx] = mergedColumns[idx]
+	}
+	return nil
+}
+
+// fillSeriesGaps fills the gaps of a single series
+func fillSeriesGaps(columns []*sqlColumn, queryConfig *queryConfigStruct) error {
 	timeColumn := &columns[queryConfig.FillValuesTimeColumnIndex].TimeData
-	if len(*timeColumn) < 2 {
+	if len(*timeColumn) < 2 && !(queryConfig.FillFullRange && len(*timeColumn) == 1) {
 		// gaps cannot be filled. This a not an error but a noop
 		return nil
 	}
+	if (*timeColumn)[0] == nil {
+		return fmt.Errorf("received NULL value in time column")
+	}
 
 	gapFilledColumns := make([]*sqlColumn, len(columns))
 	for idx, column := range columns {
 		gapFilledColumns[idx] = &sqlColumn{Name: column.Name, Type: column.Type}
 	}
 
-	// subtract one interval to start the gap filling after the first value
-	currentRowTime := (*timeColumn)[0].Add(
-		time.Second * -1 * time.Duration(queryConfig.FillInterval),
-	)
+	interval := time.Second * time.Duration(queryConfig.FillInterval)
+	location := queryConfig.fillLocation()
+	// the gaps are filled with the buckets of the grouping macro, which follow the clock of its
+	// timezone. Each bucket is the one after the last row (or the last filled gap)
+	var currentRowTime *time.Time
+	if queryConfig.FillFullRange {
+		// start with the bucket containing the start of the time range
+		firstBucket := alignToInterval(queryConfig.TimeRange.From, queryConfig.FillInterval, location)
+		currentRowTime = &firstBucket
+	}
 
 	for idx, timeCell := range *timeColumn {
 		if timeCell == nil {
@@ -38,25 +212,40 @@ func fillGaps(columns []*sqlColumn, queryConfig *queryConfigStruct) error {
 			)
 
 		}
-		currentRowTime = currentRowTime.Add(time.Second * time.Duration(queryConfig.FillInterval))
 
-		for currentRowTime.Before(*timeCell) {
+		for currentRowTime != nil && currentRowTime.Before(*timeCell) {
 			err := addGapToColumns(
-				currentRowTime, gapFilledColumns, columns, *queryConfig, len(*timeColumn),
+				*currentRowTime, gapFilledColumns, columns, *queryConfig, len(*timeColumn), idx-1, idx,
 			)
 			if err != nil {
 				return err
 			}
-			currentRowTime = currentRowTime.Add(
-				time.Second * time.Duration(queryConfig.FillInterval),
-			)
+			next := nextWallClockBucket(*currentRowTime, interval, location)
+			currentRowTime = &next
 		}
 
 		err := addValueToColumns(idx, gapFilledColumns, columns, len(*timeColumn))
 		if err != nil {
 			return err
 		}
-		currentRowTime = *timeCell
+		next := nextWallClockBucket(*timeCell, interval, location)
+		currentRowTime = &next
+	}
+
+	if queryConfig.FillFullRange {
+		// continue with the bucket containing the end of the time range
+		lastBucket := alignToInterval(queryConfig.TimeRange.To, queryConfig.FillInterval, location)
+		lastRow := len(*timeColumn) - 1
+		for !currentRowTime.After(lastBucket) {
+			err := addGapToColumns(
+				*currentRowTime, gapFilledColumns, columns, *queryConfig, len(*timeColumn), lastRow, -1,
+			)
+			if err != nil {
+				return err
+			}
+			next := nextWallClockBucket(*currentRowTime, interval, location)
+			currentRowTime = &next
+		}
 	}
 
 	for idx := range columns {
@@ -65,28 +254,98 @@ func fillGaps(columns []*sqlColumn, queryConfig *queryConfigStruct) error {
 	return nil
 }
 
+// alignToInterval returns the start of the bucket containing the given time. The buckets are
+// the same as the ones of the grouping macros (e.g. `cast(("time" / 10) as int) * 10`)
+// applied to the clock of the location
+func alignToInterval(value time.Time, intervalSeconds int, location *time.Location) time.Time {
+	interval := int64(intervalSeconds)
+	clock := wallClock(value, location).Unix()
+	return fromWallClock(time.Unix((clock/interval)*interval, 0), location)
+}
+
+// addGapToColumns adds a row for fillTime to the new columns. The values are determined by the
+// fill mode based on the surrounding rows of the original columns (-1 if there is no such row)
 func addGapToColumns(
 	fillTime time.Time,
 	newColumns []*sqlColumn,
 	originalColumns []*sqlColumn,
 	queryConfig queryConfigStruct,
 	originalRowCount int,
+	previousRow int,
+	nextRow int,
 ) error {
-	for columnIndex := range originalColumns {
+	timeData := originalColumns[queryConfig.FillValuesTimeColumnIndex].TimeData
+	// ratio is the relative position of the gap between the previous and the next row
+	ratio := math.NaN()
+	if previousRow >= 0 && nextRow >= 0 {
+		ratio = float64(fillTime.Sub(*timeData[previousRow])) /
+			float64(timeData[nextRow].Sub(*timeData[previousRow]))
+	}
+
+	for columnIndex, column := range originalColumns {
 		if columnIndex == queryConfig.FillValuesTimeColumnIndex {
 			newColumns[columnIndex].TimeData = append(newColumns[columnIndex].TimeData, &fillTime)
 			continue
 		}
 
 		switch originalRowCount {
-		case len(originalColumns[columnIndex].StringData):
-			newColumns[columnIndex].StringData = append(newColumns[columnIndex].StringData, nil)
-		case len(originalColumns[columnIndex].FloatData):
-			newColumns[columnIndex].FloatData = append(newColumns[columnIndex].FloatData, nil)
-		case len(originalColumns[columnIndex].IntData):
-			newColumns[columnIndex].IntData = append(newColumns[columnIndex].IntData, nil)
-		case len(originalColumns[columnIndex].TimeData):
-			newColumns[columnIndex].TimeData = append(newColumns[columnIndex].TimeData, nil)
+		case len(column.StringData):
+			var value *string
+			switch queryConfig.FillMode {
+			case fillModePrevious:
+				value = rowValue(column.StringData, previousRow)
+			case fillModeValue:
+				value = queryConfig.FillValue.String
+			}
+			newColumns[columnIndex].StringData = append(newColumns[columnIndex].StringData, value)
+		case len(column.FloatData):
+			var value *float64
+			switch queryConfig.FillMode {
+			case fillModePrevious:
+				value = rowValue(column.FloatData, previousRow)
+			case fillModeLinear:
+				previous, next := rowValue(column.FloatData, previousRow), rowValue(column.FloatData, nextRow)
+				if previous != nil && next != nil {
+					interpolated := *previous + (*next-*previous)*ratio
+					value = &interpolated
+				}
+			case fillModeValue:
+				value = queryConfig.FillValue.Float
+			}
+			newColumns[columnIndex].FloatData = append(newColumns[columnIndex].FloatData, value)
+		case len(column.IntData):
+			var value *int64
+			switch queryConfig.FillMode {
+			case fillModePrevious:
+				value = rowValue(column.IntData, previousRow)
+			case fillModeLinear:
+				previous, next := rowValue(column.IntData, previousRow), rowValue(column.IntData, nextRow)
+				if previous != nil && next != nil {
+					interpolated := *previous + int64(math.Round(float64(*next-*previous)*ratio))
+					value = &interpolated
+				}
+			case fillModeValue:
+				value = queryConfig.FillValue.Int
+			}
+			newColumns[columnIndex].IntData = append(newColumns[columnIndex].IntData, value)
+		case len(column.TimeData):
+			var value *time.Time
+			if queryConfig.FillMode == fillModePrevious {
+				value = rowValue(column.TimeData, previousRow)
+			}
+			newColumns[columnIndex].TimeData = append(newColumns[columnIndex].TimeData, value)
+		case len(column.BoolData):
+			var value *bool
+			if queryConfig.FillMode == fillModePrevious {
+				value = rowValue(column.BoolData, previousRow)
+			}
+			newColumns[columnIndex].BoolData = append(newColumns[columnIndex].BoolData, value)
+		case len(column.JSONData):
+			var value *json.RawMessage
+			if queryConfig.FillMode == fillModePrevious {
+				value = rowValue(column.JSONData, previousRow)
+			}
+			newColumns[columnIndex].JSONData = append(newColumns[columnIndex].JSONData, value)
 		default:
 			log.DefaultLogger.Error(
 				"could not find column type to fill gap for", "rowCount", originalRowCount,
@@ -98,6 +357,14 @@ func addGapToColumns(
 	return nil
 }
 
+// rowValue returns the value of the row or nil if the row does not exist
+func rowValue[T any](values []*T, row int) *T {
+	if row < 0 || row >= len(values) {
+		return nil
+	}
+	return values[row]
+}
+
 func addValueToColumns(
 	rowIndex int, newColumns []*sqlColumn, originalColumns []*sqlColumn, originalRowCount int,
 ) error {
@@ -123,6 +390,16 @@ func addValueToColumns(
 				newColumns[columnIndex].TimeData,
 				originalColumns[columnIndex].TimeData[rowIndex],
 			)
+		case len(originalColumns[columnIndex].BoolData):
+			newColumns[columnIndex].BoolData = append(
+				newColumns[columnIndex].BoolData,
+				originalColumns[columnIndex].BoolData[rowIndex],
+			)
+		case len(originalColumns[columnIndex].JSONData):
+			newColumns[columnIndex].JSONData = append(
+				newColumns[columnIndex].JSONData,
+				originalColumns[columnIndex].JSONData[rowIndex],
+			)
 		default:
 			log.DefaultLogger.Error(
 				"could not find column type to set value (gap filling) for",
@@ -1,13 +1,13 @@
 package plugin
 
 import (
-	"context"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
@@ -25,7 +25,7 @@ func TestEpochGroupSecondsShouldBeReplacedInTheFinalQueryForTables(t *testing.T)
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -64,7 +64,7 @@ func TestEpochGroupSecondsShouldFillInNullValuesForTables(t *testing.T) {
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -110,7 +110,7 @@ func TestEpochGroupSecondsShouldFillInNullValuesForTimeSeriesWithDoubleGaps(t *t
 	})
 	dataQuery.QueryType = timeSeriesType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -167,7 +167,7 @@ func TestEpochGroupSecondsShouldFillInNullValuesWithMultipleTimeColumns(t *testi
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -218,7 +218,7 @@ func TestEpochGroupSecondsShouldBeReplacedInTheFinalQueryForTimeSeries(t *testin
 	})
 	dataQuery.QueryType = timeSeriesType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -270,18 +270,112 @@ func TestEpochGroupSecondsWithMultiFrameTimeSeriesAndGaps(t *testing.T) {
 	})
 	dataQuery.QueryType = "time series"
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
 
+	// the gaps are filled per series. Each series has a single row, so there is no gap to fill
+	expectedInputFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{unixTimePointer(10), unixTimePointer(30)}),
+		data.NewField("name", nil, []*string{strPointer("one"), strPointer("two")}),
+		data.NewField("value", nil, []*float64{floatPointer(11.1), floatPointer(22.2)}),
+	)
+	// we use the response as we do not care about the value (tested elsewhere)
+	expectedInputFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: response.Frames[0].Meta.ExecutedQueryString, Type: data.FrameTypeTimeSeriesWide, TypeVersion: [2]uint{0, 1},
+	}
+
+	if diff := cmp.Diff(expectedInputFrame, inputFrame, cmpOption...); diff != "" {
+		t.Error("Unexpected input frame into the time series conversion")
+		t.Fatal(diff)
+	}
+
+	if len(response.Frames) != 2 {
+		t.Errorf(
+			"Expected two frames but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	expectedOutputFrames := make([]*data.Frame, 2)
+	expectedOutputFrames[0] = data.NewFrame(
+		"",
+		data.NewField("window", nil, []time.Time{time.Unix(10, 0), time.Unix(30, 0)}),
+		data.NewField(
+			"value",
+			map[string]string{"name": "one"},
+			[]*float64{floatPointer(11.1), nil},
+		),
+	)
+	// we use the response as we do not care about the value (tested elsewhere)
+	expectedOutputFrames[0].Meta = response.Frames[0].Meta
+
+	expectedOutputFrames[1] = data.NewFrame(
+		"",
+		data.NewField("window", nil, []time.Time{time.Unix(10, 0), time.Unix(30, 0)}),
+		data.NewField(
+			"value",
+			map[string]string{"name": "two"},
+			[]*float64{nil, floatPointer(22.2)},
+		),
+	)
+	// we use the response as we do not care about the value (tested elsewhere)
+	expectedOutputFrames[1].Meta = response.Frames[1].Meta
+
+	for idx, frame := range response.Frames {
+		if diff := cmp.Diff(expectedOutputFrames[idx], frame, cmpOption...); diff != "" {
+			t.Error("Unexpected output frames")
+			t.Error(diff)
+		}
+	}
+}
+
+func TestEpochGroupSecondsShouldFillTheGapsOfEachSeries(t *testing.T) {
+	var inputFrame *data.Frame
+	mockableLongToWide = func(a *data.Frame, b *data.FillMissing) (*data.Frame, error) {
+		inputFrame = a
+		return data.LongToWide(a, b)
+	}
+
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value REAL, name TEXT);
+		INSERT INTO test(time, value, name)
+		VALUES (11, 11.1, 'one'), (12, 22.2, 'two'), (22, 44.4, 'two'), (32, 33.3, 'one');
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, NULL) as window, name, value
+			FROM test GROUP BY 1, name ORDER BY 1 ASC
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = "time series"
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Errorf("Unexpected error - %s", response.Error)
+	}
+
+	// the gaps are filled per series. The timestamp 20 of series "two" does not prevent
+	// filling the gap of series "one"
 	expectedInputFrame := data.NewFrame(
 		"",
 		data.NewField("window", nil, []*time.Time{
-			unixTimePointer(10), unixTimePointer(20), unixTimePointer(30),
+			unixTimePointer(10),
+			unixTimePointer(10),
+			unixTimePointer(20),
+			unixTimePointer(20),
+			unixTimePointer(30),
+		}),
+		data.NewField("name", nil, []*string{
+			strPointer("one"), strPointer("two"), strPointer("one"), strPointer("two"), strPointer("one"),
+		}),
+		data.NewField("value", nil, []*float64{
+			floatPointer(11.1), floatPointer(22.2), nil, floatPointer(44.4), floatPointer(33.3),
 		}),
-		data.NewField("name", nil, []*string{strPointer("one"), nil, strPointer("two")}),
-		data.NewField("value", nil, []*float64{floatPointer(11.1), nil, floatPointer(22.2)}),
 	)
 	// we use the response as we do not care about the value (tested elsewhere)
 	expectedInputFrame.Meta = &data.FrameMeta{
@@ -294,7 +388,7 @@ func TestEpochGroupSecondsWithMultiFrameTimeSeriesAndGaps(t *testing.T) {
 	}
 
 	if len(response.Frames) != 2 {
-		t.Errorf(
+		t.Fatalf(
 			"Expected two frames but got - %d: Frames %+v", len(response.Frames), response.Frames,
 		)
 	}
@@ -308,7 +402,7 @@ func TestEpochGroupSecondsWithMultiFrameTimeSeriesAndGaps(t *testing.T) {
 		data.NewField(
 			"value",
 			map[string]string{"name": "one"},
-			[]*float64{floatPointer(11.1), nil, nil},
+			[]*float64{floatPointer(11.1), nil, floatPointer(33.3)},
 		),
 	)
 	// we use the response as we do not care about the value (tested elsewhere)
@@ -322,7 +416,7 @@ func TestEpochGroupSecondsWithMultiFrameTimeSeriesAndGaps(t *testing.T) {
 		data.NewField(
 			"value",
 			map[string]string{"name": "two"},
-			[]*float64{nil, nil, floatPointer(22.2)},
+			[]*float64{floatPointer(22.2), floatPointer(44.4), nil},
 		),
 	)
 	// we use the response as we do not care about the value (tested elsewhere)
@@ -336,6 +430,66 @@ func TestEpochGroupSecondsWithMultiFrameTimeSeriesAndGaps(t *testing.T) {
 	}
 }
 
+func TestEpochGroupSecondsShouldFillEachSeriesWithPreviousValues(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, sensor TEXT);
+		INSERT INTO test(time, value, sensor)
+		VALUES (1, 1, 'a'), (2, 5, 'b'), (22, 3, 'a'), (32, 7, 'b');
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, previous, range) as window, sensor, value
+			FROM test ORDER BY 1 ASC
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = "time series"
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(30, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	if len(response.Frames) != 2 {
+		t.Fatalf(
+			"Expected two frames but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	windows := []time.Time{time.Unix(0, 0), time.Unix(10, 0), time.Unix(20, 0), time.Unix(30, 0)}
+	expectedOutputFrames := []*data.Frame{
+		data.NewFrame(
+			"",
+			data.NewField("window", nil, windows),
+			data.NewField(
+				"value",
+				map[string]string{"sensor": "a"},
+				[]*int64{intPointer(1), intPointer(1), intPointer(3), intPointer(3)},
+			),
+		),
+		data.NewFrame(
+			"",
+			data.NewField("window", nil, windows),
+			data.NewField(
+				"value",
+				map[string]string{"sensor": "b"},
+				[]*int64{intPointer(5), intPointer(5), intPointer(5), intPointer(7)},
+			),
+		),
+	}
+
+	for idx, frame := range response.Frames {
+		// we use the response as we do not care about the value (tested elsewhere)
+		expectedOutputFrames[idx].Meta = frame.Meta
+		if diff := cmp.Diff(expectedOutputFrames[idx], frame, cmpOption...); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
 func TestEpochGroupSecondsShouldNotAcceptOneArgument(t *testing.T) {
 	dataQuery := getDataQuery(queryModel{
 		QueryText:   `SELECT $__unixEpochGroupSeconds("time") as window, value FROM test`,
@@ -343,7 +497,7 @@ func TestEpochGroupSecondsShouldNotAcceptOneArgument(t *testing.T) {
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: "dbPath"}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: "dbPath"})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -360,7 +514,7 @@ func TestEpochGroupSecondsShouldNotAcceptAnyStringAsGapValue(t *testing.T) {
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: "dbPath"}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: "dbPath"})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -384,7 +538,7 @@ func TestEpochGroupSecondsShouldRequireATimeColumnForGapFilling(t *testing.T) {
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -408,7 +562,7 @@ func TestEpochGroupSecondsShouldRequireAnOrderedTimeColumnForGapFilling(t *testi
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -434,7 +588,7 @@ func TestEpochGroupSecondsShouldRequireNonNullTimeColumnForGapFilling(t *testing
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -445,3 +599,453 @@ func TestEpochGroupSecondsShouldRequireNonNullTimeColumnForGapFilling(t *testing
 		t.Errorf("Expected null in time column error but got: %+v", response.Error)
 	}
 }
+
+func TestEpochGroupSecondsShouldFillInPreviousValues(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, name TEXT, ratio REAL);
+		INSERT INTO test(time, value, name, ratio)
+		VALUES (4, 1, 'one', 0.5), (13, 2, 'two', NULL), (44, 5, 'five', 1.5);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, previous) as window, value, name, ratio
+			FROM test
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0),
+			unixTimePointer(10),
+			unixTimePointer(20),
+			unixTimePointer(30),
+			unixTimePointer(40),
+		}),
+		data.NewField("value", nil, []*int64{
+			intPointer(1), intPointer(2), intPointer(2), intPointer(2), intPointer(5),
+		}),
+		data.NewField("name", nil, []*string{
+			strPointer("one"), strPointer("two"), strPointer("two"), strPointer("two"), strPointer("five"),
+		}),
+		data.NewField("ratio", nil, []*float64{floatPointer(0.5), nil, nil, nil, floatPointer(1.5)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		// we test this content elsewhere and do not care about it
+		ExecutedQueryString: response.Frames[0].Meta.ExecutedQueryString,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldFillInConstantValues(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, name TEXT, ratio REAL);
+		INSERT INTO test(time, value, name, ratio)
+		VALUES (4, 1, 'one', 0.5), (24, 3, 'three', 1.5);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, 0) as window, value, name, ratio FROM test
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0), unixTimePointer(10), unixTimePointer(20),
+		}),
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(0), intPointer(3)}),
+		// a number is not used to fill text columns
+		data.NewField("name", nil, []*string{strPointer("one"), nil, strPointer("three")}),
+		data.NewField("ratio", nil, []*float64{floatPointer(0.5), floatPointer(0), floatPointer(1.5)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		// we test this content elsewhere and do not care about it
+		ExecutedQueryString: response.Frames[0].Meta.ExecutedQueryString,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldFillInConstantStrings(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, name TEXT);
+		INSERT INTO test(time, value, name)
+		VALUES (4, 1, 'one'), (24, 3, 'three');
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, 'it''s missing') as window, value, name
+			FROM test
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0), unixTimePointer(10), unixTimePointer(20),
+		}),
+		data.NewField("value", nil, []*int64{intPointer(1), nil, intPointer(3)}),
+		data.NewField("name", nil, []*string{
+			strPointer("one"), strPointer("it's missing"), strPointer("three"),
+		}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		// we test this content elsewhere and do not care about it
+		ExecutedQueryString: response.Frames[0].Meta.ExecutedQueryString,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldInterpolateLinearly(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, name TEXT, ratio REAL);
+		INSERT INTO test(time, value, name, ratio)
+		VALUES (4, 1, 'one', 0.5), (13, 2, 'two', 1.0), (44, 5, 'five', 2.5);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, linear) as window, value, name, ratio
+			FROM test
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0),
+			unixTimePointer(10),
+			unixTimePointer(20),
+			unixTimePointer(30),
+			unixTimePointer(40),
+		}),
+		data.NewField("value", nil, []*int64{
+			intPointer(1), intPointer(2), intPointer(3), intPointer(4), intPointer(5),
+		}),
+		// only numbers are interpolated
+		data.NewField("name", nil, []*string{
+			strPointer("one"), strPointer("two"), nil, nil, strPointer("five"),
+		}),
+		data.NewField("ratio", nil, []*float64{
+			floatPointer(0.5), floatPointer(1), floatPointer(1.5), floatPointer(2), floatPointer(2.5),
+		}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		// we test this content elsewhere and do not care about it
+		ExecutedQueryString: response.Frames[0].Meta.ExecutedQueryString,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldFillTheFullTimeRange(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER);
+		INSERT INTO test(time, value) VALUES (24, 2), (33, 3);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   `SELECT $__unixEpochGroupSeconds("time", 10, NULL, range) as window, value FROM test`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(5, 0), To: time.Unix(61, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0),
+			unixTimePointer(10),
+			unixTimePointer(20),
+			unixTimePointer(30),
+			unixTimePointer(40),
+			unixTimePointer(50),
+			unixTimePointer(60),
+		}),
+		data.NewField("value", nil, []*int64{nil, nil, intPointer(2), intPointer(3), nil, nil, nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: `SELECT cast(("time" / 10) as int) * 10 as window, value FROM test`,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldFillTheFullTimeRangeForASingleRow(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER);
+		INSERT INTO test(time, value) VALUES (14, 2);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   `SELECT $__unixEpochGroupSeconds("time", 10, previous, RANGE) as window, value FROM test`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = tableType
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(30, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*time.Time{
+			unixTimePointer(0),
+			unixTimePointer(10),
+			unixTimePointer(20),
+			unixTimePointer(30),
+		}),
+		// there is no previous value for the first bucket
+		data.NewField("value", nil, []*int64{nil, intPointer(2), intPointer(2), intPointer(2)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: `SELECT cast(("time" / 10) as int) * 10 as window, value FROM test`,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochGroupSecondsShouldRejectAnUnknownFillRange(t *testing.T) {
+	queryConfig := queryConfigStruct{
+		FinalQuery: `SELECT $__unixEpochGroupSeconds("time", 10, NULL, everything) FROM test`,
+	}
+
+	err := applyMacros(&queryConfig)
+	if err == nil || !strings.Contains(err.Error(), "unsupported gap filling range") {
+		t.Errorf("Expected gap filling range error but got: %v", err)
+	}
+}
+
+func TestEpochGroupSecondsShouldKeepTheLabelsOfGapsBeforeTheFirstRow(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, sensor TEXT);
+		INSERT INTO test(time, value, sensor)
+		VALUES (12, 1, 'a'), (22, 5, 'b');
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT $__unixEpochGroupSeconds("time", 10, 0, range) as window, sensor, value
+			FROM test ORDER BY 1 ASC
+		`,
+		TimeColumns: []string{"window"},
+	})
+	dataQuery.QueryType = "time series"
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(30, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	// both series start with a gap, which needs the labels of the series
+	if len(response.Frames) != 2 {
+		t.Fatalf(
+			"Expected two frames but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	windows := []time.Time{time.Unix(0, 0), time.Unix(10, 0), time.Unix(20, 0), time.Unix(30, 0)}
+	expectedOutputFrames := []*data.Frame{
+		data.NewFrame(
+			"",
+			data.NewField("window", nil, windows),
+			data.NewField(
+				"value",
+				map[string]string{"sensor": "a"},
+				[]*int64{intPointer(0), intPointer(1), intPointer(0), intPointer(0)},
+			),
+		),
+		data.NewFrame(
+			"",
+			data.NewField("window", nil, windows),
+			data.NewField(
+				"value",
+				map[string]string{"sensor": "b"},
+				[]*int64{intPointer(0), intPointer(0), intPointer(5), intPointer(0)},
+			),
+		),
+	}
+
+	for idx, frame := range response.Frames {
+		// we use the response as we do not care about the value (tested elsewhere)
+		expectedOutputFrames[idx].Meta = frame.Meta
+		if diff := cmp.Diff(expectedOutputFrames[idx], frame, cmpOption...); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestEpochGroupSecondsShouldNotFillInferredTimeColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER, day TEXT);
+		INSERT INTO test(time, value, day)
+		VALUES (4, 1, '2024-01-01'), (13, 2, '2024-01-02'), (44, 5, '2024-01-05');
+	`)
+	defer cleanup()
+
+	// the computed column max(day) is inferred to be a time column, but the gaps are in window
+	dataQuery := getDataQuery(queryModel{
+		QueryText: `
+			SELECT max(day) as last_day, $__unixEpochGroupSeconds("time", 10, NULL) as window, value
+			FROM test GROUP BY 2 ORDER BY 2
+		`,
+		TimeColumns: []string{"window"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	if len(response.Frames) != 1 {
+		t.Fatalf(
+			"Expected one frame but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField(
+			"last_day",
+			nil,
+			[]*time.Time{
+				timePointer(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				timePointer(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+				nil,
+				nil,
+				timePointer(time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)),
+			},
+		),
+		data.NewField(
+			"window",
+			nil,
+			[]*time.Time{
+				unixTimePointer(0),
+				unixTimePointer(10),
+				unixTimePointer(20),
+				unixTimePointer(30),
+				unixTimePointer(40),
+			},
+		),
+		data.NewField(
+			"value", nil, []*int64{intPointer(1), intPointer(2), nil, nil, intPointer(5)},
+		),
+	)
+
+	// we use the response as we do not care about the executed query (tested elsewhere)
+	expectedFrame.Meta = response.Frames[0].Meta
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestEpochMsGroupShouldFillInNullValues(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER);
+		INSERT INTO test(time, value)
+		VALUES (1612325104000, 1), (1612325113500, 2), (1612325144999, 5);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   `SELECT $__unixEpochMsGroup("time", 10, NULL) as window, value FROM test`,
+		TimeColumns: []string{"window"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	if len(response.Frames) != 1 {
+		t.Fatalf(
+			"Expected one frame but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField(
+			"window",
+			nil,
+			[]*time.Time{
+				unixTimePointer(1612325100),
+				unixTimePointer(1612325110),
+				unixTimePointer(1612325120),
+				unixTimePointer(1612325130),
+				unixTimePointer(1612325140),
+			},
+		),
+		data.NewField(
+			"value", nil, []*int64{intPointer(1), intPointer(2), nil, nil, intPointer(5)},
+		),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: `SELECT cast(("time" / 10000) as int) * 10000 as window, value FROM test`,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
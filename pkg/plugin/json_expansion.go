@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// expandJSONColumns replaces the JSON columns with one column per (dotted) path of their objects.
+// The depth limits how many levels of nested objects are flattened (0 means no limit). Deeper
+// objects and arrays are kept as JSON values
+func expandJSONColumns(columns []*sqlColumn, depths map[string]int) ([]*sqlColumn, error) {
+	if len(depths) == 0 {
+		return columns, nil
+	}
+
+	result := []*sqlColumn{}
+	for _, column := range columns {
+		depth, expand := depths[column.Name]
+		if !expand {
+			result = append(result, column)
+			continue
+		}
+		if column.Type != "STRING" && column.Type != "JSON" {
+			return nil, fmt.Errorf(
+				"the column %s cannot be expanded as it contains %s values",
+				column.Name, strings.ToLower(column.Type),
+			)
+		}
+		result = append(result, expandJSONColumn(column, depth)...)
+	}
+	return result, nil
+}
+
+// expandJSONColumn flattens the JSON objects of the column. Rows without a JSON object (e.g. NULL
+// or invalid JSON) have no values in the expanded columns
+func expandJSONColumn(column *sqlColumn, depth int) []*sqlColumn {
+	rowCount := max(len(column.StringData), len(column.JSONData))
+	paths := []string{}
+	values := map[string][]interface{}{}
+
+	for row := 0; row < rowCount; row++ {
+		value := columnValueString(column, row)
+		if value == nil {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader([]byte(*value)))
+		decoder.UseNumber()
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil || object == nil {
+			continue
+		}
+
+		flattened := map[string]interface{}{}
+		flattenJSONObject(column.Name, object, 1, depth, flattened)
+		for _, path := range slices.Sorted(maps.Keys(flattened)) {
+			if values[path] == nil {
+				paths = append(paths, path)
+				values[path] = make([]interface{}, rowCount)
+			}
+			values[path][row] = flattened[path]
+		}
+	}
+
+	if len(paths) == 0 {
+		// without any object the column is kept to not change the fields of the frame
+		return []*sqlColumn{column}
+	}
+
+	expanded := []*sqlColumn{}
+	for _, path := range paths {
+		expanded = append(expanded, jsonValuesColumn(path, values[path]))
+	}
+	return expanded
+}
+
+// flattenJSONObject adds the values of the object by their dotted path. Objects are flattened
+// until the depth is reached (0 means no limit)
+func flattenJSONObject(
+	prefix string, object map[string]interface{}, level int, depth int, result map[string]interface{},
+) {
+	for key, value := range object {
+		path := prefix + "." + key
+		if nested, isObject := value.(map[string]interface{}); isObject && (depth <= 0 || level < depth) {
+			flattenJSONObject(path, nested, level+1, depth, result)
+			continue
+		}
+		result[path] = value
+	}
+}
+
+// jsonValuesColumn creates a column of the decoded JSON values. The type of the column is the
+// type of all values (numbers are integers if all of them are integral). Columns with values of
+// different types and columns of objects or arrays are JSON columns
+func jsonValuesColumn(name string, values []interface{}) *sqlColumn {
+	columnType := ""
+	for _, value := range values {
+		valueType := ""
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			valueType = "STRING"
+		case bool:
+			valueType = "BOOL"
+		case json.Number:
+			valueType = "FLOAT"
+			if _, err := v.Int64(); err == nil {
+				valueType = "INTEGER"
+			}
+		default:
+			valueType = "JSON"
+		}
+
+		switch {
+		case columnType == "" || columnType == valueType:
+			columnType = valueType
+		case (columnType == "INTEGER" || columnType == "FLOAT") &&
+			(valueType == "INTEGER" || valueType == "FLOAT"):
+			columnType = "FLOAT"
+		default:
+			columnType = "JSON"
+		}
+	}
+	if columnType == "" {
+		// the path only has null values
+		columnType = "JSON"
+	}
+
+	column := &sqlColumn{Name: name, Type: columnType}
+	for _, value := range values {
+		switch columnType {
+		case "STRING":
+			column.StringData = append(column.StringData, typedValue[string](value))
+		case "BOOL":
+			column.BoolData = append(column.BoolData, typedValue[bool](value))
+		case "INTEGER":
+			var cell *int64
+			if number, isNumber := value.(json.Number); isNumber {
+				if parsed, err := number.Int64(); err == nil {
+					cell = &parsed
+				}
+			}
+			column.IntData = append(column.IntData, cell)
+		case "FLOAT":
+			var cell *float64
+			if number, isNumber := value.(json.Number); isNumber {
+				if parsed, err := number.Float64(); err == nil {
+					cell = &parsed
+				}
+			}
+			column.FloatData = append(column.FloatData, cell)
+		default:
+			var cell *json.RawMessage
+			if value != nil {
+				if raw, err := json.Marshal(value); err == nil {
+					rawMessage := json.RawMessage(raw)
+					cell = &rawMessage
+				}
+			}
+			column.JSONData = append(column.JSONData, cell)
+		}
+	}
+	return column
+}
+
+// typedValue returns a pointer to the value if it has the type (and nil otherwise)
+func typedValue[T any](value interface{}) *T {
+	if typed, ok := value.(T); ok {
+		return &typed
+	}
+	return nil
+}
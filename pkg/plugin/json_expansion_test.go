@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestExpandJSONColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE events(name TEXT, payload TEXT);
+		INSERT INTO events VALUES
+			('login', '{"user": {"id": 1, "address": {"city": "Berlin"}}, "ok": true, "tags": ["a"]}'),
+			('logout', '{"user": {"id": 2.5}, "ok": false, "extra": null}'),
+			('broken', 'no json'),
+			('empty', NULL);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT name, payload FROM events"
+	dataQuery := getDataQuery(queryModel{
+		QueryText: queryText, ExpandJSONColumns: map[string]int{"payload": 2},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	rawJSON := func(value string) *json.RawMessage {
+		raw := json.RawMessage(value)
+		return &raw
+	}
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("name", nil, []*string{
+			strPointer("login"), strPointer("logout"), strPointer("broken"), strPointer("empty"),
+		}),
+		data.NewField("payload.ok", nil, []*bool{boolPointer(true), boolPointer(false), nil, nil}),
+		data.NewField("payload.tags", nil, []*json.RawMessage{rawJSON(`["a"]`), nil, nil, nil}),
+		data.NewField("payload.user.address", nil, []*json.RawMessage{
+			rawJSON(`{"city":"Berlin"}`), nil, nil, nil,
+		}),
+		data.NewField("payload.user.id", nil, []*float64{floatPointer(1), floatPointer(2.5), nil, nil}),
+		data.NewField("payload.extra", nil, []*json.RawMessage{nil, nil, nil, nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestExpandJSONColumnsShouldRequireTextColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT 1 AS payload", ExpandJSONColumns: map[string]int{"payload": 0},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "cannot be expanded") {
+		t.Errorf("Expected an error about the expanded column but got %v", response.Error)
+	}
+}
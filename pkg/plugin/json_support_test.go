@@ -1,7 +1,6 @@
 package plugin
 
 import (
-	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -18,7 +17,7 @@ func TestJsonSupport(t *testing.T) {
 	baseQuery := `SELECT json_array_length('[1,2,3,4]') as value;`
 	dataQuery := getDataQuery(queryModel{QueryText: baseQuery})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
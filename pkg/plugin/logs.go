@@ -0,0 +1,318 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// logsType is the query type of log queries. The columns with the following names are converted
+// to the logs format of Grafana. All other columns are labels of the log lines
+const logsType = "logs"
+
+// logsVolumeType counts the log lines of a logs query per level (the "logs volume" of Grafana)
+const logsVolumeType = "logs volume"
+
+const (
+	logTimeColumn  = "time"
+	logBodyColumn  = "body"
+	logLevelColumn = "level"
+	// logIDColumn is an optional unique identifier of the log lines
+	logIDColumn = "id"
+)
+
+// unknownLogLevel is the level of log lines without a level
+const unknownLogLevel = "unknown"
+
+// defaultLogsVolumeBuckets is the number of buckets of the logs volume if the query has no interval
+const defaultLogsVolumeBuckets = 100
+
+// logContextBackward is the direction of log context queries for older log lines
+const logContextBackward = "backward"
+
+// logContextQuery selects the log lines before or after a log line ("show context" of Grafana)
+type logContextQuery struct {
+	// Time is the time of the log line in unix milliseconds
+	Time int64 `json:"time"`
+	// Direction is backward (older log lines) or forward (newer log lines, the default)
+	Direction string `json:"direction"`
+	// Limit is the maximum number of returned log lines (0 means no limit)
+	Limit int `json:"limit"`
+}
+
+// prepareLogsQuery sets the types of the log columns
+func prepareLogsQuery(queryConfig *queryConfigStruct) {
+	queryConfig.TimeColumns = append(slices.Clone(queryConfig.TimeColumns), logTimeColumn)
+
+	columnTypes := maps.Clone(queryConfig.ColumnTypes)
+	if columnTypes == nil {
+		columnTypes = map[string]string{}
+	}
+	columnTypes[logTimeColumn] = "time"
+	columnTypes[logBodyColumn] = "string"
+	columnTypes[logLevelColumn] = "string"
+	columnTypes[logIDColumn] = "string"
+	queryConfig.ColumnTypes = columnTypes
+}
+
+// logColumns are the columns of a logs query by their meaning
+type logColumns struct {
+	time   *sqlColumn
+	body   *sqlColumn
+	level  *sqlColumn
+	id     *sqlColumn
+	labels []*sqlColumn
+}
+
+func newLogColumns(columns []*sqlColumn) (logColumns, error) {
+	var result logColumns
+	for _, column := range columns {
+		switch column.Name {
+		case logTimeColumn:
+			result.time = column
+		case logBodyColumn:
+			result.body = column
+		case logLevelColumn:
+			result.level = column
+		case logIDColumn:
+			result.id = column
+		default:
+			result.labels = append(result.labels, column)
+		}
+	}
+
+	if result.time == nil || result.body == nil {
+		return result, fmt.Errorf(
+			"a logs query needs the columns %s and %s", logTimeColumn, logBodyColumn,
+		)
+	}
+	return result, nil
+}
+
+// level returns the level of the row or the unknown level
+func (columns logColumns) levelOf(row int) string {
+	if columns.level == nil {
+		return unknownLogLevel
+	}
+	if level := rowValue(columns.level.StringData, row); level != nil && *level != "" {
+		return strings.ToLower(*level)
+	}
+	return unknownLogLevel
+}
+
+// logsFrame creates a frame in the logs format of Grafana
+// (https://grafana.com/developers/dataplane/logs). Log lines without a time are skipped
+func logsFrame(
+	columns []*sqlColumn, queryConfig *queryConfigStruct, context *logContextQuery,
+) (*data.Frame, error) {
+	logs, err := newLogColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := logRows(logs.time.TimeData, context)
+	timestamps := make([]time.Time, 0, len(rows))
+	bodies := make([]string, 0, len(rows))
+	levels := make([]string, 0, len(rows))
+	ids := make([]string, 0, len(rows))
+	labels := make([]json.RawMessage, 0, len(rows))
+
+	for _, row := range rows {
+		timestamps = append(timestamps, *logs.time.TimeData[row])
+
+		body := ""
+		if value := rowValue(logs.body.StringData, row); value != nil {
+			body = *value
+		}
+		bodies = append(bodies, body)
+		levels = append(levels, logs.levelOf(row))
+
+		if logs.id != nil {
+			id := ""
+			if value := rowValue(logs.id.StringData, row); value != nil {
+				id = *value
+			}
+			ids = append(ids, id)
+		}
+
+		rowLabels := map[string]string{}
+		for _, column := range logs.labels {
+			if value := columnValueString(column, row); value != nil {
+				rowLabels[column.Name] = *value
+			}
+		}
+		rawLabels, err := json.Marshal(rowLabels)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, rawLabels)
+	}
+
+	frame := data.NewFrame(
+		"",
+		data.NewField("timestamp", nil, timestamps),
+		data.NewField("body", nil, bodies),
+		data.NewField("severity", nil, levels),
+	)
+	if logs.id != nil {
+		frame.Fields = append(frame.Fields, data.NewField("id", nil, ids))
+	}
+	frame.Fields = append(frame.Fields, data.NewField("labels", nil, labels))
+
+	frame.Meta = queryConfig.frameMeta()
+	frame.Meta.Type = data.FrameTypeLogLines
+	frame.Meta.TypeVersion = data.FrameTypeVersion{0, 0}
+	frame.Meta.PreferredVisualization = data.VisTypeLogs
+
+	return frame, nil
+}
+
+// logRows returns the rows of the log lines with a time. For context queries only the rows
+// before (sorted descending) or after (sorted ascending) the time of the context are returned
+func logRows(times []*time.Time, context *logContextQuery) []int {
+	var contextTime time.Time
+	backward := false
+	if context != nil {
+		contextTime = time.UnixMilli(context.Time)
+		backward = context.Direction == logContextBackward
+	}
+
+	rows := []int{}
+	for row, value := range times {
+		if value == nil {
+			continue
+		}
+		if context != nil && backward && !value.Before(contextTime) {
+			continue
+		}
+		if context != nil && !backward && !value.After(contextTime) {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if context != nil {
+		sort.SliceStable(rows, func(a, b int) bool {
+			if backward {
+				return times[rows[a]].After(*times[rows[b]])
+			}
+			return times[rows[a]].Before(*times[rows[b]])
+		})
+		if context.Limit > 0 && len(rows) > context.Limit {
+			rows = rows[:context.Limit]
+		}
+	}
+
+	return rows
+}
+
+// logsVolumeFrames counts the log lines per level in buckets of the interval of the query.
+// Each level is a separate frame with the label level. Empty buckets have a count of 0
+func logsVolumeFrames(
+	columns []*sqlColumn, queryConfig *queryConfigStruct, dataQuery backend.DataQuery,
+) ([]*data.Frame, error) {
+	logs, err := newLogColumns(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := queryInterval(dataQuery)
+	if interval <= 0 {
+		interval = gtime.RoundInterval(queryConfig.TimeRange.Duration() / defaultLogsVolumeBuckets)
+	}
+	intervalSeconds := max(int(interval.Round(time.Second)/time.Second), 1)
+	interval = time.Duration(intervalSeconds) * time.Second
+	location := queryConfig.location()
+
+	buckets := []time.Time{}
+	bucketIndexes := map[int64]int{}
+	bucket := alignToInterval(queryConfig.TimeRange.From, intervalSeconds, location)
+	lastBucket := alignToInterval(queryConfig.TimeRange.To, intervalSeconds, location)
+	for !bucket.After(lastBucket) {
+		bucketIndexes[bucket.Unix()] = len(buckets)
+		buckets = append(buckets, bucket)
+		bucket = nextWallClockBucket(bucket, interval, location)
+	}
+
+	counts := map[string][]int64{}
+	for row, value := range logs.time.TimeData {
+		if value == nil {
+			continue
+		}
+		bucketIndex, exists := bucketIndexes[alignToInterval(*value, intervalSeconds, location).Unix()]
+		if !exists {
+			// the log line is outside of the time range
+			continue
+		}
+
+		level := logs.levelOf(row)
+		if counts[level] == nil {
+			counts[level] = make([]int64, len(buckets))
+		}
+		counts[level][bucketIndex]++
+	}
+
+	frames := []*data.Frame{}
+	for _, level := range slices.Sorted(maps.Keys(counts)) {
+		countField := data.NewField("count", data.Labels{"level": level}, counts[level])
+		countField.Config = &data.FieldConfig{DisplayNameFromDS: level}
+
+		frame := data.NewFrame("", data.NewField("time", nil, buckets), countField)
+		frame.Meta = queryConfig.frameMeta()
+		frame.Meta.Type = data.FrameTypeTimeSeriesMulti
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// columnValueString formats the value of a row as string (or nil for NULL values)
+func columnValueString(column *sqlColumn, row int) *string {
+	var value string
+	switch column.Type {
+	case "TIME":
+		cell := rowValue(column.TimeData, row)
+		if cell == nil {
+			return nil
+		}
+		value = cell.Format(time.RFC3339Nano)
+	case "INTEGER":
+		cell := rowValue(column.IntData, row)
+		if cell == nil {
+			return nil
+		}
+		value = strconv.FormatInt(*cell, 10)
+	case "FLOAT":
+		cell := rowValue(column.FloatData, row)
+		if cell == nil {
+			return nil
+		}
+		value = strconv.FormatFloat(*cell, 'f', -1, 64)
+	case "STRING":
+		return rowValue(column.StringData, row)
+	case "BOOL":
+		cell := rowValue(column.BoolData, row)
+		if cell == nil {
+			return nil
+		}
+		value = strconv.FormatBool(*cell)
+	case "JSON":
+		cell := rowValue(column.JSONData, row)
+		if cell == nil {
+			return nil
+		}
+		value = string(*cell)
+	default:
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const logsTestSeed = `
+	CREATE TABLE logs(ts INTEGER, message TEXT, severity TEXT, host TEXT, pid INTEGER);
+	INSERT INTO logs(ts, message, severity, host, pid)
+	VALUES	(10, 'started', 'INFO', 'web-1', 42),
+			(70, 'slow request', 'warn', 'web-1', NULL),
+			(80, 'failed', 'error', 'web-2', 43),
+			(200, 'no level', NULL, NULL, NULL);
+`
+
+const logsTestQuery = `
+	SELECT ts AS time, message AS body, severity AS level, host, pid, rowid AS id
+	FROM logs ORDER BY ts
+`
+
+func TestLogsQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(logsTestSeed)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: logsTestQuery})
+	dataQuery.QueryType = logsType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 1 {
+		t.Fatalf("Expected one frame but got %d", len(response.Frames))
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("timestamp", nil, []time.Time{
+			time.Unix(10, 0), time.Unix(70, 0), time.Unix(80, 0), time.Unix(200, 0),
+		}),
+		data.NewField("body", nil, []string{"started", "slow request", "failed", "no level"}),
+		data.NewField("severity", nil, []string{"info", "warn", "error", "unknown"}),
+		data.NewField("id", nil, []string{"1", "2", "3", "4"}),
+		data.NewField("labels", nil, []json.RawMessage{
+			json.RawMessage(`{"host":"web-1","pid":"42"}`),
+			json.RawMessage(`{"host":"web-1"}`),
+			json.RawMessage(`{"host":"web-2","pid":"43"}`),
+			json.RawMessage(`{}`),
+		}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeLogLines,
+		TypeVersion:            data.FrameTypeVersion{0, 0},
+		PreferredVisualization: data.VisTypeLogs,
+		ExecutedQueryString:    logsTestQuery,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLogsContextQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(logsTestSeed)
+	defer cleanup()
+
+	for _, testCase := range []struct {
+		context  logContextQuery
+		expected []string
+	}{
+		{
+			context:  logContextQuery{Time: 80000, Direction: "backward", Limit: 10},
+			expected: []string{"slow request", "started"},
+		},
+		{
+			context:  logContextQuery{Time: 80000, Direction: "backward", Limit: 1},
+			expected: []string{"slow request"},
+		},
+		{
+			context:  logContextQuery{Time: 10000, Direction: "forward"},
+			expected: []string{"slow request", "failed", "no level"},
+		},
+	} {
+		dataQuery := getDataQuery(queryModel{QueryText: logsTestQuery, LogContext: &testCase.context})
+		dataQuery.QueryType = logsType
+
+		response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+		if response.Error != nil {
+			t.Fatalf("Unexpected error - %s", response.Error)
+		}
+
+		if diff := cmp.Diff(
+			data.NewField("body", nil, testCase.expected), response.Frames[0].Fields[1], cmpOption...,
+		); diff != "" {
+			t.Errorf("Unexpected lines for %+v: %s", testCase.context, diff)
+		}
+	}
+}
+
+func TestLogsVolumeQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(logsTestSeed)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: logsTestQuery})
+	dataQuery.QueryType = logsVolumeType
+	dataQuery.Interval = time.Minute
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(179, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	buckets := []time.Time{time.Unix(0, 0), time.Unix(60, 0), time.Unix(120, 0)}
+	expectedCounts := map[string][]int64{
+		"error": {0, 1, 0},
+		"info":  {1, 0, 0},
+		"warn":  {0, 1, 0},
+	}
+	if len(response.Frames) != len(expectedCounts) {
+		t.Fatalf("Expected %d frames but got %d", len(expectedCounts), len(response.Frames))
+	}
+
+	for idx, level := range []string{"error", "info", "warn"} {
+		countField := data.NewField("count", data.Labels{"level": level}, expectedCounts[level])
+		countField.Config = &data.FieldConfig{DisplayNameFromDS: level}
+
+		if diff := cmp.Diff(
+			[]*data.Field{data.NewField("time", nil, buckets), countField},
+			response.Frames[idx].Fields,
+			cmpOption...,
+		); diff != "" {
+			t.Errorf("Unexpected volume of %s: %s", level, diff)
+		}
+	}
+}
+
+func TestLogsQueryShouldRequireTimeAndBody(t *testing.T) {
+	dbPath, cleanup := createTmpDB(logsTestSeed)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT ts AS time, message FROM logs"})
+	dataQuery.QueryType = logsType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "needs the columns time and body") {
+		t.Errorf("Expected a missing column error but got %v", response.Error)
+	}
+}
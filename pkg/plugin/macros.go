@@ -5,82 +5,345 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 )
 
-const macroRegex = `\$__([_a-zA-Z0-9]+)\(([^\)]*)\)`
+// macroStartRegex matches the name and the opening parenthesis of a macro. The arguments are
+// parsed by splitMacroArguments, as they can contain parentheses themselves
+var macroStartRegex = regexp.MustCompile(`\$__([_a-zA-Z0-9]+)\(`)
 
 func applyMacros(queryConfig *queryConfigStruct) error {
-	compiledRegex, err := regexp.Compile(macroRegex)
-	if err != nil {
-		log.DefaultLogger.Error("Could create macro regex", "err", err)
-		return err
-	}
-
+	query := queryConfig.FinalQuery
 	newQuery := ""
 	lastReplacedIndex := 0
 
-	for _, match := range compiledRegex.FindAllSubmatchIndex([]byte(queryConfig.FinalQuery), -1) {
-		groups := []string{}
+	for offset := 0; offset < len(query); {
+		match := macroStartRegex.FindStringSubmatchIndex(query[offset:])
+		if match == nil {
+			break
+		}
+		macroStart := offset + match[0]
+		macro := query[offset+match[2] : offset+match[3]]
 
-		for i := 0; i < len(match); i += 2 {
-			groups = append(groups, queryConfig.FinalQuery[match[i]:match[i+1]])
+		arguments, macroEnd, closed := splitMacroArguments(query, offset+match[1])
+		if !closed {
+			// macros without a closing parenthesis are left unchanged
+			break
 		}
 
+		timeRange := queryConfig.TimeRange
+		location := queryConfig.location()
 		var replacedString string
-		switch groups[1] {
-		case "unixEpochGroupSeconds":
-			replacedString, err = unixEpochGroupSeconds(queryConfig, strings.Split(groups[2], ","))
-			if err != nil {
-				return err
-			}
+		var err error
+		switch macro {
+		case "unixEpochGroupSeconds", "unixEpochGroup":
+			replacedString, err = unixEpochGroupSeconds(queryConfig, arguments)
+		case "unixEpochGroupAlias":
+			replacedString, err = unixEpochGroupSeconds(queryConfig, arguments)
+			replacedString += ` AS "time"`
+		case "unixEpochMsGroup":
+			replacedString, err = unixEpochGroupMs(queryConfig, arguments)
+		case "unixEpochMsGroupAlias":
+			replacedString, err = unixEpochGroupMs(queryConfig, arguments)
+			replacedString += ` AS "time"`
+		case "timeGroup":
+			replacedString, err = timeGroup(queryConfig, arguments)
+		case "timeGroupAlias":
+			replacedString, err = timeGroup(queryConfig, arguments)
+			replacedString += ` AS "time"`
+		case "timeFilter":
+			replacedString, err = timeFilter(timeRange, location, arguments)
+		case "unixEpochFilter":
+			replacedString, err = unixEpochFilter(timeRange, macro, arguments, time.Second)
+		case "unixEpochMsFilter":
+			replacedString, err = unixEpochFilter(timeRange, macro, arguments, time.Millisecond)
+		case "unixEpochNanoFilter":
+			replacedString, err = unixEpochFilter(timeRange, macro, arguments, time.Nanosecond)
+		case "timeFrom":
+			replacedString, err = timeBoundary(timeRange.From.In(location), macro, arguments)
+		case "timeTo":
+			replacedString, err = timeBoundary(timeRange.To.In(location), macro, arguments)
+		case "unixEpochFrom":
+			replacedString, err = unixEpochBoundary(timeRange.From, macro, arguments, time.Second)
+		case "unixEpochTo":
+			replacedString, err = unixEpochBoundary(timeRange.To, macro, arguments, time.Second)
+		case "unixEpochMsFrom":
+			replacedString, err = unixEpochBoundary(timeRange.From, macro, arguments, time.Millisecond)
+		case "unixEpochMsTo":
+			replacedString, err = unixEpochBoundary(timeRange.To, macro, arguments, time.Millisecond)
+		case "unixEpochNanoFrom":
+			replacedString, err = unixEpochBoundary(timeRange.From, macro, arguments, time.Nanosecond)
+		case "unixEpochNanoTo":
+			replacedString, err = unixEpochBoundary(timeRange.To, macro, arguments, time.Nanosecond)
 		default:
-			replacedString = groups[0]
+			// the arguments of unknown macros can contain macros
+			offset += match[1]
+			continue
+		}
+		if err != nil {
+			return err
 		}
 
-		newQuery += queryConfig.FinalQuery[lastReplacedIndex:match[0]] + replacedString
-		lastReplacedIndex = match[1]
+		newQuery += query[lastReplacedIndex:macroStart] + replacedString
+		lastReplacedIndex = macroEnd
+		offset = macroEnd
 	}
 
-	queryConfig.FinalQuery = newQuery + queryConfig.FinalQuery[lastReplacedIndex:]
+	queryConfig.FinalQuery = newQuery + query[lastReplacedIndex:]
 
 	return nil
 }
 
+// splitMacroArguments splits the arguments of a macro, which start after its opening parenthesis,
+// at the commas outside of parentheses, strings and quoted identifiers (e.g. the argument
+// strftime('%s', time) is not split). It returns the end of the macro after its closing
+// parenthesis and whether the macro is closed at all
+func splitMacroArguments(query string, start int) ([]string, int, bool) {
+	arguments := []string{}
+	argumentStart := start
+	depth := 0
+
+	for idx := start; idx < len(query); idx++ {
+		switch char := query[idx]; char {
+		case '\'', '"', '`', '[':
+			closing := char
+			if char == '[' {
+				closing = ']'
+			}
+			// quotes are escaped by doubling them, which is the same as two adjacent strings
+			end := strings.IndexByte(query[idx+1:], closing)
+			if end == -1 {
+				return nil, 0, false
+			}
+			idx += end + 1
+		case '(':
+			depth++
+		case ',':
+			if depth == 0 {
+				arguments = append(arguments, strings.TrimSpace(query[argumentStart:idx]))
+				argumentStart = idx + 1
+			}
+		case ')':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			lastArgument := strings.TrimSpace(query[argumentStart:idx])
+			// macros without arguments, e.g. $__timeFrom()
+			if len(arguments) > 0 || lastArgument != "" {
+				arguments = append(arguments, lastArgument)
+			}
+			return arguments, idx + 1, true
+		}
+	}
+
+	return nil, 0, false
+}
+
 func unixEpochGroupSeconds(queryConfig *queryConfigStruct, arguments []string) (string, error) {
-	if len(arguments) < 2 || len(arguments) > 3 {
-		return "", fmt.Errorf(
-			"unsupported number of arguments (%d) for unixEpochGroupSeconds", len(arguments),
+	err := parseGroupingArguments(queryConfig, "unixEpochGroupSeconds", arguments)
+	if err != nil {
+		return "", err
+	}
+	// unix timestamps have no timezone, so the buckets are aligned in UTC
+	queryConfig.FillLocation = time.UTC
+
+	return fmt.Sprintf(
+		"cast((%s / %d) as int) * %d",
+		arguments[0],
+		queryConfig.FillInterval,
+		queryConfig.FillInterval,
+	), nil
+}
+
+// unixEpochGroupMs groups a column with unix timestamps in milliseconds. The interval is given in
+// seconds like for unixEpochGroupSeconds
+func unixEpochGroupMs(queryConfig *queryConfigStruct, arguments []string) (string, error) {
+	err := parseGroupingArguments(queryConfig, "unixEpochMsGroup", arguments)
+	if err != nil {
+		return "", err
+	}
+	queryConfig.FillLocation = time.UTC
+
+	interval := queryConfig.FillInterval * 1000
+	return fmt.Sprintf("cast((%s / %d) as int) * %d", arguments[0], interval, interval), nil
+}
+
+// timeGroup groups a column with ISO-8601 formatted strings into buckets of unix timestamps.
+// The buckets are aligned to the clock of the configured timezone (e.g. days start at midnight)
+func timeGroup(queryConfig *queryConfigStruct, arguments []string) (string, error) {
+	err := parseGroupingArguments(queryConfig, "timeGroup", arguments)
+	if err != nil {
+		return "", err
+	}
+	queryConfig.FillLocation = queryConfig.location()
+
+	bucket := fmt.Sprintf(
+		"cast((unixepoch(%s) / %d) as int) * %d",
+		arguments[0],
+		queryConfig.FillInterval,
+		queryConfig.FillInterval,
+	)
+	// buckets next to the time range are included (e.g. because of the gap filling range)
+	margin := 24*time.Hour + time.Duration(queryConfig.FillInterval)*time.Second
+	return wallClockToUnix(bucket, queryConfig.TimeRange, margin, queryConfig.location()), nil
+}
+
+// parseGroupingArguments parses the arguments (column, interval and optional gap filling value)
+// of the grouping macros and sets the gap filling configuration of the query
+func parseGroupingArguments(queryConfig *queryConfigStruct, macro string, arguments []string) error {
+	if len(arguments) < 2 || len(arguments) > 4 {
+		return fmt.Errorf(
+			"unsupported number of arguments (%d) for %s", len(arguments), macro,
 		)
 	}
 	var err error
-	queryConfig.FillInterval, err = strconv.Atoi(strings.Trim(arguments[1], " "))
+	queryConfig.FillInterval, err = parseGroupingInterval(arguments[1])
 	if err != nil {
 		log.DefaultLogger.Error(
-			"Could not convert grouping interval to an integer",
+			"Could not convert grouping interval to seconds",
 			"macro",
-			"unixEpochGroupSeconds",
+			macro,
 			"err",
 			err,
 		)
-		return "", fmt.Errorf(
-			"could not convert '%s' to an integer grouping interval", arguments[1],
+		return fmt.Errorf(
+			"could not convert '%s' to a grouping interval in seconds", arguments[1],
 		)
 	}
 
 	// the gap filling value
-	if len(arguments) == 3 {
-		if strings.ToLower(strings.Trim(arguments[2], " ")) != "null" {
-			return "", fmt.Errorf("unsupported gap filling value of: `%s`", arguments[2])
+	if len(arguments) >= 3 {
+		queryConfig.FillMode, queryConfig.FillValue, err = parseGapFillArgument(arguments[2])
+		if err != nil {
+			return err
 		}
 		queryConfig.ShouldFillValues = true
 	}
 
+	// the gap filling range. By default only the gaps between the first and last row are filled
+	if len(arguments) == 4 {
+		if strings.ToLower(arguments[3]) != fillRangeFull {
+			return fmt.Errorf("unsupported gap filling range of: `%s`", arguments[3])
+		}
+		queryConfig.FillFullRange = true
+	}
+
+	return nil
+}
+
+// parseGapFillArgument parses the gap filling argument of the grouping macros. Supported are
+// NULL, previous (the last value is carried forward), linear (linear interpolation of numbers)
+// and constants (numbers or strings in single quotes)
+func parseGapFillArgument(argument string) (string, gapFillValue, error) {
+	switch strings.ToLower(argument) {
+	case fillModeNull:
+		return fillModeNull, gapFillValue{}, nil
+	case fillModePrevious:
+		return fillModePrevious, gapFillValue{}, nil
+	case fillModeLinear:
+		return fillModeLinear, gapFillValue{}, nil
+	}
+
+	if len(argument) >= 2 && strings.HasPrefix(argument, "'") && strings.HasSuffix(argument, "'") {
+		value := strings.ReplaceAll(argument[1:len(argument)-1], "''", "'")
+		return fillModeValue, gapFillValue{String: &value}, nil
+	}
+
+	floatValue, err := strconv.ParseFloat(argument, 64)
+	if err != nil {
+		return "", gapFillValue{}, fmt.Errorf("unsupported gap filling value of: `%s`", argument)
+	}
+	fillValue := gapFillValue{Float: &floatValue}
+	if intValue := int64(floatValue); float64(intValue) == floatValue {
+		fillValue.Int = &intValue
+	}
+
+	return fillModeValue, fillValue, nil
+}
+
+// parseGroupingInterval parses an integer number of seconds or a Grafana duration
+// string (e.g. 5m or '1h') to seconds. As the grouping works with whole seconds, shorter
+// intervals are rounded up to one second
+func parseGroupingInterval(argument string) (int, error) {
+	var duration time.Duration
+	if seconds, err := strconv.Atoi(argument); err == nil {
+		duration = time.Duration(seconds) * time.Second
+	} else {
+		duration, err = gtime.ParseDuration(strings.Trim(argument, `'"`))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("the grouping interval needs to be positive")
+	}
+
+	return int(max(duration.Round(time.Second), time.Second) / time.Second), nil
+}
+
+// timeFilter filters a column with ISO-8601 formatted strings by the time range of the query.
+// Strings without an offset are compared with the clock readings of the time range in the
+// location
+func timeFilter(
+	timeRange backend.TimeRange, location *time.Location, arguments []string,
+) (string, error) {
+	if len(arguments) != 1 {
+		return "", fmt.Errorf("unsupported number of arguments (%d) for timeFilter", len(arguments))
+	}
+
 	return fmt.Sprintf(
-		"cast((%s / %d) as int) * %d",
+		"unixepoch(%s, 'subsec') BETWEEN %s AND %s",
 		arguments[0],
-		queryConfig.FillInterval,
-		queryConfig.FillInterval,
+		formatUnixSeconds(wallClock(timeRange.From, location)),
+		formatUnixSeconds(wallClock(timeRange.To, location)),
+	), nil
+}
+
+// unixEpochFilter filters a column with unix timestamps (in the given unit) by the time range
+// of the query
+func unixEpochFilter(
+	timeRange backend.TimeRange, macro string, arguments []string, unit time.Duration,
+) (string, error) {
+	if len(arguments) != 1 {
+		return "", fmt.Errorf("unsupported number of arguments (%d) for %s", len(arguments), macro)
+	}
+
+	return fmt.Sprintf(
+		"%s BETWEEN %d AND %d",
+		arguments[0],
+		timeRange.From.UnixNano()/int64(unit),
+		timeRange.To.UnixNano()/int64(unit),
 	), nil
 }
+
+// timeBoundary formats the boundary of the time range like datetime() (with fractional seconds
+// if any), so that it can be compared as text with the values of SQLite's date and time functions
+// and CURRENT_TIMESTAMP. The boundary is the clock reading in the location of the time
+func timeBoundary(boundary time.Time, macro string, arguments []string) (string, error) {
+	if len(arguments) != 0 {
+		return "", fmt.Errorf("unsupported number of arguments (%d) for %s", len(arguments), macro)
+	}
+
+	return fmt.Sprintf("'%s'", boundary.Format(timeStringLayout)), nil
+}
+
+func unixEpochBoundary(
+	boundary time.Time, macro string, arguments []string, unit time.Duration,
+) (string, error) {
+	if len(arguments) != 0 {
+		return "", fmt.Errorf("unsupported number of arguments (%d) for %s", len(arguments), macro)
+	}
+
+	return strconv.FormatInt(boundary.UnixNano()/int64(unit), 10), nil
+}
+
+// formatUnixSeconds formats the time as unix seconds with millisecond precision
+func formatUnixSeconds(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
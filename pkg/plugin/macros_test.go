@@ -0,0 +1,386 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var macroTestTimeRange = backend.TimeRange{
+	From: time.Date(2021, 2, 3, 4, 5, 6, 789000000, time.UTC),
+	To:   time.Date(2021, 2, 3, 5, 5, 6, 0, time.UTC),
+}
+
+func TestTimeMacros(t *testing.T) {
+	for _, testCase := range []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM t WHERE $__timeFilter(ts)",
+			expected: "SELECT * FROM t WHERE unixepoch(ts, 'subsec') BETWEEN 1612325106.789 AND 1612328706",
+		},
+		{
+			query:    "SELECT * FROM t WHERE $__unixEpochFilter(ts)",
+			expected: "SELECT * FROM t WHERE ts BETWEEN 1612325106 AND 1612328706",
+		},
+		{
+			query:    "SELECT * FROM t WHERE $__unixEpochMsFilter(ts)",
+			expected: "SELECT * FROM t WHERE ts BETWEEN 1612325106789 AND 1612328706000",
+		},
+		{
+			query:    "SELECT * FROM t WHERE $__unixEpochNanoFilter(ts)",
+			expected: "SELECT * FROM t WHERE ts BETWEEN 1612325106789000000 AND 1612328706000000000",
+		},
+		{
+			query:    "SELECT $__timeFrom(), $__timeTo()",
+			expected: "SELECT '2021-02-03 04:05:06.789', '2021-02-03 05:05:06'",
+		},
+		{
+			query:    "SELECT $__unixEpochFrom(), $__unixEpochTo()",
+			expected: "SELECT 1612325106, 1612328706",
+		},
+		{
+			query:    "SELECT $__unixEpochNanoFrom(), $__unixEpochNanoTo( )",
+			expected: "SELECT 1612325106789000000, 1612328706000000000",
+		},
+		{
+			query:    "SELECT $__timeGroup(ts, 300) FROM t",
+			expected: "SELECT cast((unixepoch(ts) / 300) as int) * 300 FROM t",
+		},
+		{
+			query:    "SELECT $__timeGroupAlias(ts, 300) FROM t",
+			expected: `SELECT cast((unixepoch(ts) / 300) as int) * 300 AS "time" FROM t`,
+		},
+		{
+			query:    "SELECT $__unixEpochGroup(ts, 300) FROM t",
+			expected: "SELECT cast((ts / 300) as int) * 300 FROM t",
+		},
+		{
+			query:    "SELECT $__unixEpochGroupAlias(ts, 300) FROM t",
+			expected: `SELECT cast((ts / 300) as int) * 300 AS "time" FROM t`,
+		},
+		{
+			query:    "SELECT $__unixEpochMsGroup(ts, 300) FROM t",
+			expected: "SELECT cast((ts / 300000) as int) * 300000 FROM t",
+		},
+		{
+			query:    "SELECT $__unixEpochMsGroupAlias(ts, '1m') FROM t",
+			expected: `SELECT cast((ts / 60000) as int) * 60000 AS "time" FROM t`,
+		},
+		{
+			query:    "SELECT $__unixEpochMsFrom(), $__unixEpochMsTo()",
+			expected: "SELECT 1612325106789, 1612328706000",
+		},
+		{
+			query:    "SELECT $__unixEpochGroup(strftime('%s', t), 60) FROM t",
+			expected: "SELECT cast((strftime('%s', t) / 60) as int) * 60 FROM t",
+		},
+		{
+			query:    "SELECT * FROM t WHERE $__unixEpochFilter(coalesce(ts, ')', \"a,b\", [c)]))",
+			expected: "SELECT * FROM t WHERE coalesce(ts, ')', \"a,b\", [c)]) BETWEEN 1612325106 AND 1612328706",
+		},
+		{
+			query:    "SELECT $__unknownMacro(ts) FROM t",
+			expected: "SELECT $__unknownMacro(ts) FROM t",
+		},
+		{
+			query:    "SELECT $__unknownMacro($__unixEpochFrom(), (a)) FROM t",
+			expected: "SELECT $__unknownMacro(1612325106, (a)) FROM t",
+		},
+		{
+			query:    "SELECT $__unixEpochFrom(, $__unixEpochTo( FROM t",
+			expected: "SELECT $__unixEpochFrom(, $__unixEpochTo( FROM t",
+		},
+	} {
+		queryConfig := queryConfigStruct{
+			FinalQuery: testCase.query, TimeRange: macroTestTimeRange,
+		}
+
+		err := applyMacros(&queryConfig)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", testCase.query, err)
+			continue
+		}
+
+		if queryConfig.FinalQuery != testCase.expected {
+			t.Errorf(
+				"Unexpected query for %s. Expected: %s. Got: %s",
+				testCase.query, testCase.expected, queryConfig.FinalQuery,
+			)
+		}
+	}
+}
+
+func TestTimeMacrosShouldRejectInvalidArguments(t *testing.T) {
+	for _, query := range []string{
+		"SELECT $__timeFilter()",
+		"SELECT $__timeFilter(a, b)",
+		"SELECT $__unixEpochFilter()",
+		"SELECT $__timeFrom(a)",
+		"SELECT $__unixEpochTo(a)",
+		"SELECT $__timeGroup(a)",
+		"SELECT $__timeGroup(strftime('%s', a))",
+		"SELECT $__unixEpochMsGroup(a)",
+		"SELECT $__unixEpochMsFrom(a)",
+	} {
+		queryConfig := queryConfigStruct{FinalQuery: query, TimeRange: macroTestTimeRange}
+
+		err := applyMacros(&queryConfig)
+		if err == nil || !strings.Contains(err.Error(), "unsupported number of arguments") {
+			t.Errorf("Expected argument error for %s but got: %v", query, err)
+		}
+	}
+}
+
+func TestTimeGroupShouldFillInNullValues(t *testing.T) {
+	queryConfig := queryConfigStruct{
+		FinalQuery: "SELECT $__timeGroup(ts, 60, NULL) FROM t",
+		TimeRange:  macroTestTimeRange,
+	}
+
+	err := applyMacros(&queryConfig)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	if !queryConfig.ShouldFillValues || queryConfig.FillInterval != 60 {
+		t.Errorf("Expected gap filling with an interval of 60 but got %+v", queryConfig)
+	}
+}
+
+func TestTimeFilterWithISOStrings(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts TEXT, value INTEGER);
+		INSERT INTO test(ts, value)
+		VALUES	('2021-02-03T04:05:06Z', 1),
+				('2021-02-03 04:30:00', 2),
+				('2021-02-03T06:35:00+02:00', 3),
+				('2021-02-03T05:05:07Z', 4);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT value FROM test WHERE $__timeFilter(ts) ORDER BY value",
+	})
+	dataQuery.TimeRange = backend.TimeRange{
+		From: time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC),
+		To:   time.Date(2021, 2, 3, 5, 5, 6, 0, time.UTC),
+	}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(2), intPointer(3)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: "SELECT value FROM test WHERE " +
+			"unixepoch(ts, 'subsec') BETWEEN 1612325106 AND 1612328706 ORDER BY value",
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestTimeBoundariesShouldBeComparableWithDatetime(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts TEXT, value INTEGER);
+		INSERT INTO test(ts, value)
+		VALUES	('2024-01-02 08:59:59', 1),
+				('2024-01-02 10:00:00', 2),
+				('2024-01-02 11:00:00', 3),
+				('2024-01-02 11:00:01', 4);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT value FROM test WHERE ts >= $__timeFrom() AND ts <= $__timeTo() ORDER BY value",
+	})
+	dataQuery.TimeRange = backend.TimeRange{
+		From: time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 2, 11, 0, 0, 0, time.UTC),
+	}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("value", nil, []*int64{intPointer(2), intPointer(3)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: "SELECT value FROM test WHERE " +
+			"ts >= '2024-01-02 09:00:00' AND ts <= '2024-01-02 11:00:00' ORDER BY value",
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestTimeMacrosWithTimezone tests the macros for time strings without an offset in a timezone
+// with a daylight saving time change (2021-03-28 02:00 in Europe/Berlin)
+func TestTimeMacrosWithTimezone(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Could not load timezone - %s", err)
+	}
+
+	for _, testCase := range []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "SELECT * FROM t WHERE $__timeFilter(ts)",
+			expected: "SELECT * FROM t WHERE unixepoch(ts, 'subsec') BETWEEN 1616850000 AND 1616940000",
+		},
+		{
+			query:    "SELECT $__timeFrom(), $__timeTo()",
+			expected: "SELECT '2021-03-27 13:00:00', '2021-03-28 14:00:00'",
+		},
+		{
+			query: "SELECT $__timeGroup(ts, 1d) FROM t",
+			expected: "SELECT CASE " +
+				"WHEN cast((unixepoch(ts) / 86400) as int) * 86400 < 1616896800 " +
+				"THEN cast((unixepoch(ts) / 86400) as int) * 86400 - 3600 " +
+				"ELSE cast((unixepoch(ts) / 86400) as int) * 86400 - 7200 END FROM t",
+		},
+		{
+			query:    "SELECT $__unixEpochFilter(ts), $__unixEpochGroup(ts, 300) FROM t",
+			expected: "SELECT ts BETWEEN 1616846400 AND 1616932800, cast((ts / 300) as int) * 300 FROM t",
+		},
+	} {
+		queryConfig := queryConfigStruct{
+			FinalQuery: testCase.query,
+			TimeRange: backend.TimeRange{
+				From: time.Date(2021, 3, 27, 12, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 3, 28, 12, 0, 0, 0, time.UTC),
+			},
+			Location: location,
+		}
+
+		err := applyMacros(&queryConfig)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", testCase.query, err)
+			continue
+		}
+
+		if queryConfig.FinalQuery != testCase.expected {
+			t.Errorf(
+				"Unexpected query for %s. Expected: %s. Got: %s",
+				testCase.query, testCase.expected, queryConfig.FinalQuery,
+			)
+		}
+	}
+}
+
+func TestGroupingIntervals(t *testing.T) {
+	for _, testCase := range []struct {
+		argument string
+		expected int
+	}{
+		{argument: "10", expected: 10},
+		{argument: "5m", expected: 300},
+		{argument: "'1h'", expected: 3600},
+		{argument: `"1d"`, expected: 86400},
+		{argument: "500ms", expected: 1},
+		{argument: "90s", expected: 90},
+	} {
+		interval, err := parseGroupingInterval(testCase.argument)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", testCase.argument, err)
+		}
+		if interval != testCase.expected {
+			t.Errorf("Expected %d for %s but got %d", testCase.expected, testCase.argument, interval)
+		}
+	}
+
+	for _, argument := range []string{"", "abc", "-5m", "0s", "0", "-10"} {
+		if _, err := parseGroupingInterval(argument); err == nil {
+			t.Errorf("Expected error for %s but got nothing", argument)
+		}
+	}
+}
+
+func TestEpochGroupSecondsWithTheIntervalVariable(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(time INTEGER, value INTEGER);
+		INSERT INTO test(time, value) VALUES (4, 1), (65, 2), (70, 4);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT $__unixEpochGroupSeconds(time, $__interval) AS window, sum(value) AS value " +
+			"FROM test GROUP BY window ORDER BY window",
+	})
+	dataQuery.Interval = time.Minute
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("window", nil, []*int64{intPointer(0), intPointer(60)}),
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(6)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: "SELECT cast((time / 60) as int) * 60 AS window, sum(value) AS value " +
+			"FROM test GROUP BY window ORDER BY window",
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestGapFillArguments(t *testing.T) {
+	for _, testCase := range []struct {
+		argument     string
+		expectedMode string
+		expected     gapFillValue
+	}{
+		{argument: "NULL", expectedMode: fillModeNull},
+		{argument: "Previous", expectedMode: fillModePrevious},
+		{argument: "linear", expectedMode: fillModeLinear},
+		{
+			argument:     "0",
+			expectedMode: fillModeValue,
+			expected:     gapFillValue{Int: intPointer(0), Float: floatPointer(0)},
+		},
+		{argument: "-1.5", expectedMode: fillModeValue, expected: gapFillValue{Float: floatPointer(-1.5)}},
+		{argument: "'n/a'", expectedMode: fillModeValue, expected: gapFillValue{String: strPointer("n/a")}},
+		{argument: "''''", expectedMode: fillModeValue, expected: gapFillValue{String: strPointer("'")}},
+	} {
+		mode, value, err := parseGapFillArgument(testCase.argument)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", testCase.argument, err)
+			continue
+		}
+		if mode != testCase.expectedMode {
+			t.Errorf("Expected mode %s for %s but got %s", testCase.expectedMode, testCase.argument, mode)
+		}
+		if diff := cmp.Diff(testCase.expected, value); diff != "" {
+			t.Errorf("Unexpected value for %s: %s", testCase.argument, diff)
+		}
+	}
+
+	for _, argument := range []string{"", "next", "'unterminated", `"double"`} {
+		if _, _, err := parseGapFillArgument(argument); err == nil {
+			t.Errorf("Expected error for %s but got nothing", argument)
+		}
+	}
+}
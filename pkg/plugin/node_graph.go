@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// nodeGraphType is the query type of node graph queries. The query returns the nodes and the
+// edges query (or the rows with the kind edge) returns the edges of the graph
+const nodeGraphType = "node graph"
+
+// nodeGraphKindColumn distinguishes the nodes and edges of a single query. Its values are node
+// and edge. Rows with other values are skipped
+const nodeGraphKindColumn = "kind"
+
+const (
+	nodeGraphKindNode = "node"
+	nodeGraphKindEdge = "edge"
+)
+
+const (
+	nodeGraphIDColumn     = "id"
+	nodeGraphSourceColumn = "source"
+	nodeGraphTargetColumn = "target"
+)
+
+// nodeGraphFieldNames are the field names of the node graph of Grafana. Grafana matches them
+// case sensitive, so columns with these names are renamed (e.g. mainStat to mainstat)
+var nodeGraphFieldNames = []string{
+	"id", "title", "subtitle", "mainstat", "secondarystat", "color", "icon", "noderadius",
+	"highlighted", "source", "target", "thickness", "strokedasharray",
+}
+
+// prepareNodeGraphQuery sets the types of the identifiers of nodes and edges
+func prepareNodeGraphQuery(queryConfig *queryConfigStruct) {
+	columnTypes := maps.Clone(queryConfig.ColumnTypes)
+	if columnTypes == nil {
+		columnTypes = map[string]string{}
+	}
+	columnTypes[nodeGraphIDColumn] = "string"
+	columnTypes[nodeGraphSourceColumn] = "string"
+	columnTypes[nodeGraphTargetColumn] = "string"
+	columnTypes[nodeGraphKindColumn] = "string"
+	queryConfig.ColumnTypes = columnTypes
+}
+
+// newEdgesQueryConfig creates the configuration of the edges query, which shares all settings
+// with the nodes query
+func newEdgesQueryConfig(queryConfig queryConfigStruct, edgesQuery string) queryConfigStruct {
+	queryConfig.BaseQuery = edgesQuery
+	queryConfig.FinalQuery = edgesQuery
+	queryConfig.Arguments = nil
+	return queryConfig
+}
+
+// fetchEdges executes the edges query of a node graph query on the connection of the nodes query
+func fetchEdges(
+	conn *sql.Conn,
+	edgesConfig *queryConfigStruct,
+	dataQuery backend.DataQuery,
+	ctx context.Context,
+) ([]*sqlColumn, error) {
+	err := replaceVariables(edgesConfig, dataQuery)
+	if err != nil {
+		return nil, err
+	}
+	err = applyMacros(edgesConfig)
+	if err != nil {
+		return nil, err
+	}
+	return fetchData(conn, edgesConfig, ctx)
+}
+
+// nodeGraphFrames creates the nodes and edges frames of the node graph of Grafana. Without
+// edge columns the nodes and edges are distinguished by the kind column
+func nodeGraphFrames(
+	nodeColumns []*sqlColumn,
+	nodesConfig *queryConfigStruct,
+	edgeColumns []*sqlColumn,
+	edgesConfig *queryConfigStruct,
+) ([]*data.Frame, error) {
+	if edgeColumns == nil {
+		for _, column := range nodeColumns {
+			if column.Name != nodeGraphKindColumn {
+				continue
+			}
+			nodeRows, edgeRows := nodeGraphRows(column)
+			edgeColumns = columnsWithRows(nodeColumns, edgeRows)
+			nodeColumns = columnsWithRows(nodeColumns, nodeRows)
+			edgesConfig = nodesConfig
+			break
+		}
+	}
+
+	frames := []*data.Frame{}
+	nodes, err := nodeGraphFrame("nodes", nodeColumns, nodesConfig, nodeGraphIDColumn)
+	if err != nil {
+		return nil, err
+	}
+	frames = append(frames, nodes)
+
+	if edgeColumns != nil {
+		edges, err := nodeGraphFrame(
+			"edges", edgeColumns, edgesConfig,
+			nodeGraphIDColumn, nodeGraphSourceColumn, nodeGraphTargetColumn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, edges)
+	}
+
+	return frames, nil
+}
+
+// nodeGraphRows returns the rows of the nodes and of the edges
+func nodeGraphRows(kindColumn *sqlColumn) (nodeRows []int, edgeRows []int) {
+	nodeRows, edgeRows = []int{}, []int{}
+	for row, kind := range kindColumn.StringData {
+		if kind == nil {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(*kind)) {
+		case nodeGraphKindNode:
+			nodeRows = append(nodeRows, row)
+		case nodeGraphKindEdge:
+			edgeRows = append(edgeRows, row)
+		}
+	}
+	return nodeRows, edgeRows
+}
+
+// columnsWithRows copies the given rows of the columns. The kind column and columns with only
+// NULL values in these rows (e.g. source and target for the nodes) are removed
+func columnsWithRows(columns []*sqlColumn, rows []int) []*sqlColumn {
+	result := []*sqlColumn{}
+	for _, column := range columns {
+		if column.Name == nodeGraphKindColumn {
+			continue
+		}
+
+		selected := &sqlColumn{Name: column.Name, Type: column.Type}
+		hasValues := false
+		for _, row := range rows {
+			selected.TimeData = append(selected.TimeData, rowValue(column.TimeData, row))
+			selected.IntData = append(selected.IntData, rowValue(column.IntData, row))
+			selected.FloatData = append(selected.FloatData, rowValue(column.FloatData, row))
+			selected.StringData = append(selected.StringData, rowValue(column.StringData, row))
+			selected.BoolData = append(selected.BoolData, rowValue(column.BoolData, row))
+			selected.JSONData = append(selected.JSONData, rowValue(column.JSONData, row))
+			hasValues = hasValues || columnValueString(column, row) != nil
+		}
+
+		if hasValues || column.Name == nodeGraphIDColumn || len(rows) == 0 {
+			result = append(result, selected)
+		}
+	}
+	return result
+}
+
+// nodeGraphFrame creates a frame of the node graph with the required columns
+func nodeGraphFrame(
+	name string, columns []*sqlColumn, queryConfig *queryConfigStruct, requiredColumns ...string,
+) (*data.Frame, error) {
+	for _, column := range columns {
+		lowerName := strings.ToLower(column.Name)
+		for _, fieldName := range nodeGraphFieldNames {
+			if lowerName == fieldName {
+				column.Name = fieldName
+			}
+		}
+	}
+
+	for _, required := range requiredColumns {
+		found := false
+		for _, column := range columns {
+			found = found || column.Name == required
+		}
+		if !found {
+			return nil, fmt.Errorf(
+				"the %s of a node graph query need the columns %s",
+				name, strings.Join(requiredColumns, ", "),
+			)
+		}
+	}
+
+	frame := columnsToFrame(columns)
+	frame.Name = name
+	frame.Meta = queryConfig.frameMeta()
+	frame.Meta.PreferredVisualization = data.VisTypeNodeGraph
+	return frame, nil
+}
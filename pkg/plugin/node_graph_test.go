@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestNodeGraphQueryWithEdgesQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE hosts(id INTEGER, name TEXT, load FLOAT);
+		INSERT INTO hosts VALUES (1, 'router', 0.5), (2, 'server', 0.25);
+		CREATE TABLE links(id INTEGER, source INTEGER, target INTEGER, mbits INTEGER);
+		INSERT INTO links VALUES (10, 1, 2, 100);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT id, name AS title, load AS mainStat FROM hosts"
+	edgesQueryText := "SELECT id, source, target, mbits AS mainStat FROM links"
+	dataQuery := getDataQuery(queryModel{QueryText: queryText, EdgesQueryText: edgesQueryText})
+	dataQuery.QueryType = nodeGraphType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 2 {
+		t.Fatalf("Expected two frames but got %d", len(response.Frames))
+	}
+
+	expectedNodes := data.NewFrame(
+		"nodes",
+		data.NewField("id", nil, []*string{strPointer("1"), strPointer("2")}),
+		data.NewField("title", nil, []*string{strPointer("router"), strPointer("server")}),
+		data.NewField("mainstat", nil, []*float64{floatPointer(0.5), floatPointer(0.25)}),
+	)
+	expectedNodes.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeNodeGraph,
+		ExecutedQueryString:    queryText,
+	}
+	expectedEdges := data.NewFrame(
+		"edges",
+		data.NewField("id", nil, []*string{strPointer("10")}),
+		data.NewField("source", nil, []*string{strPointer("1")}),
+		data.NewField("target", nil, []*string{strPointer("2")}),
+		data.NewField("mainstat", nil, []*int64{intPointer(100)}),
+	)
+	expectedEdges.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeNodeGraph,
+		ExecutedQueryString:    edgesQueryText,
+	}
+
+	if diff := cmp.Diff(expectedNodes, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(expectedEdges, response.Frames[1], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNodeGraphQueryWithKindColumn(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE topology(kind TEXT, id TEXT, title TEXT, source TEXT, target TEXT);
+		INSERT INTO topology VALUES
+			('node', 'a', 'router', NULL, NULL),
+			('edge', 'a-b', NULL, 'a', 'b'),
+			('node', 'b', 'server', NULL, NULL),
+			('comment', 'c', 'ignored', NULL, NULL);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT * FROM topology"
+	dataQuery := getDataQuery(queryModel{QueryText: queryText})
+	dataQuery.QueryType = nodeGraphType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 2 {
+		t.Fatalf("Expected two frames but got %d", len(response.Frames))
+	}
+
+	expectedNodes := data.NewFrame(
+		"nodes",
+		data.NewField("id", nil, []*string{strPointer("a"), strPointer("b")}),
+		data.NewField("title", nil, []*string{strPointer("router"), strPointer("server")}),
+	)
+	expectedNodes.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeNodeGraph,
+		ExecutedQueryString:    queryText,
+	}
+	expectedEdges := data.NewFrame(
+		"edges",
+		data.NewField("id", nil, []*string{strPointer("a-b")}),
+		data.NewField("source", nil, []*string{strPointer("a")}),
+		data.NewField("target", nil, []*string{strPointer("b")}),
+	)
+	expectedEdges.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeNodeGraph,
+		ExecutedQueryString:    queryText,
+	}
+
+	if diff := cmp.Diff(expectedNodes, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(expectedEdges, response.Frames[1], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestNodeGraphQueryShouldRequireTheEdgeColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT 'a' AS id", EdgesQueryText: "SELECT 'a-b' AS id, 'a' AS source",
+	})
+	dataQuery.QueryType = nodeGraphType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "id, source, target") {
+		t.Errorf("Expected an error about the edge columns but got %v", response.Error)
+	}
+}
@@ -1,12 +1,14 @@
 package plugin
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -14,29 +16,91 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
-	"modernc.org/sqlite"
 )
 
 var mockableLongToWide = data.LongToWide
 
+// errQueryTimeout is the cause of queries cancelled by the configured query timeout
+var errQueryTimeout = errors.New("query timeout")
+
 const timeSeriesType = "time series"
 const tableType = "table"
 
 type queryConfigStruct struct {
 	BaseQuery   string
 	TimeColumns []string
+	BoolColumns []string
+	ColumnTypes map[string]string
 	QueryType   string
 	FinalQuery  string
+	TimeRange   backend.TimeRange
+	// TimeFormat (a Go layout) and EpochUnit configure the parsing of time columns
+	TimeFormat string
+	EpochUnit  string
+	// Location is the timezone of time strings without an offset
+	Location *time.Location
+	// BlobFormats are the formats of BLOB values by column name (UTF-8 by default)
+	BlobFormats map[string]string
+	// MaxBlobSize is the maximum number of bytes of BLOB values. Larger values are truncated
+	MaxBlobSize int
+	// Arguments are bound to the parameters (?) of the final query
+	Arguments []interface{}
+	// RawColumns keep the values of the database and are never converted to times (e.g. the key
+	// of a stream, which is compared with its stored values)
+	RawColumns []string
 
 	ShouldFillValues          bool
 	FillInterval              int
 	FillValuesTimeColumnIndex int
+	FillMode                  string
+	FillValue                 gapFillValue
+	FillFullRange             bool
+	// FillLocation is the timezone of the clock, which the buckets of the grouping macro are aligned
+	// to. Only $__timeGroup follows the configured timezone, unix epochs are grouped in UTC
+	FillLocation *time.Location
+
+	// MaxRows is the maximum number of rows read from the result (0 means no limit)
+	MaxRows int
+	// Notices are attached to the returned frames, e.g. to inform about truncated results
+	Notices []data.Notice
 }
 
 func (qc *queryConfigStruct) isTableType() bool {
 	return qc.QueryType != timeSeriesType
 }
 
+// location returns the timezone of time strings without an offset (UTC if not set)
+func (qc *queryConfigStruct) location() *time.Location {
+	if qc.Location == nil {
+		return time.UTC
+	}
+	return qc.Location
+}
+
+// fillLocation returns the timezone of the buckets of the gap filling (UTC if not set)
+func (qc *queryConfigStruct) fillLocation() *time.Location {
+	if qc.FillLocation == nil {
+		return time.UTC
+	}
+	return qc.FillLocation
+}
+
+func (qc *queryConfigStruct) frameMeta() *data.FrameMeta {
+	return &data.FrameMeta{ExecutedQueryString: qc.FinalQuery, Notices: qc.Notices}
+}
+
+// stricterLimit returns the lower of both limits. A limit of 0 (or below) means no limit.
+// This allows queries to lower but never to raise the limits of the datasource
+func stricterLimit(datasourceLimit int, queryLimit int) int {
+	if queryLimit <= 0 {
+		return datasourceLimit
+	}
+	if datasourceLimit <= 0 {
+		return queryLimit
+	}
+	return min(datasourceLimit, queryLimit)
+}
+
 // this struct holds a full query result column (including data)
 // the main benefit is type safety
 type sqlColumn struct {
@@ -57,10 +121,91 @@ type sqlColumn struct {
 
 	// StringData contains string values (if Type == "STRING")
 	StringData []*string
+
+	// BoolData contains boolean values (if Type == "BOOL")
+	BoolData []*bool
+
+	// JSONData contains JSON values (if Type == "JSON")
+	JSONData []*json.RawMessage
+
+	// DroppedValues counts the values, which could not be converted to the column type
+	// and were replaced with NULL
+	DroppedValues int
+	// CoercedValues counts the values, which lost precision when converting to the column type
+	CoercedValues int
+
+	// ExplicitType is set for types of the query model, which are kept even if the values
+	// lose precision (e.g. REAL values in an int column)
+	ExplicitType bool
+
+	// Raw is set for columns, whose values are not converted to times (see RawColumns)
+	Raw bool
+
+	// BlobFormat is the text format of BLOB values (see formatBlob)
+	BlobFormat string
+	// TruncatedValues counts the BLOB values, which exceeded the maximum size
+	TruncatedValues int
+}
+
+// maxExactFloatInteger is the largest integer, which is exactly representable as float64
+const maxExactFloatInteger = 1 << 53
+
+// promoteToFloat converts an integer column to a float column. This keeps REAL values in columns
+// with integers instead of truncating them
+func (column *sqlColumn) promoteToFloat() {
+	column.FloatData = make([]*float64, len(column.IntData))
+	for idx, value := range column.IntData {
+		if value == nil {
+			continue
+		}
+		if *value > maxExactFloatInteger || *value < -maxExactFloatInteger {
+			column.CoercedValues++
+		}
+		floatValue := float64(*value)
+		column.FloatData[idx] = &floatValue
+	}
+	column.IntData = nil
+	column.Type = "FLOAT"
+}
+
+// columnTypeOverrides maps the column types of the query model to the column types
+var columnTypeOverrides = map[string]string{
+	"time":   "TIME",
+	"int":    "INTEGER",
+	"float":  "FLOAT",
+	"string": "STRING",
+	"bool":   "BOOL",
+	"json":   "JSON",
+}
+
+// conversionNotices warns about values, which were changed by the conversion to the column type
+func conversionNotices(columns []*sqlColumn) []data.Notice {
+	notices := []data.Notice{}
+	for _, column := range columns {
+		if column.DroppedValues > 0 {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text: fmt.Sprintf(
+					"%d values of the column %s could not be converted to %s and were replaced with NULL",
+					column.DroppedValues, column.Name, strings.ToLower(column.Type),
+				),
+			})
+		}
+		if column.CoercedValues > 0 {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text: fmt.Sprintf(
+					"%d values of the column %s lost precision when converting to %s",
+					column.CoercedValues, column.Name, strings.ToLower(column.Type),
+				),
+			})
+		}
+	}
+	return notices
 }
 
-func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
-	columnCount := len(columns)
+// scanRow reads the raw values of the current row
+func scanRow(rows *sql.Rows, columnCount int) ([]interface{}, error) {
 	values := make([]interface{}, columnCount)
 	valuePointers := make([]interface{}, columnCount)
 
@@ -70,13 +215,19 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 
 	if err := rows.Scan(valuePointers...); err != nil {
 		log.DefaultLogger.Error("Could not scan row", "err", err)
-		return err
+		return nil, err
 	}
+	return values, nil
+}
 
+func addTransformedRow(
+	values []interface{}, columns []*sqlColumn, parser timeParser, maxBlobSize int,
+) (err error) {
 	for i, column := range columns {
 		var intV int64
 		var floatV float64
 		var stringV string
+		var timeV time.Time
 		valueType := ""
 
 		switch v := values[i].(type) {
@@ -92,6 +243,27 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 		case int64:
 			valueType = "INTEGER"
 			intV = v
+		case int:
+			valueType = "INTEGER"
+			intV = int64(v)
+		case uint8:
+			valueType = "INTEGER"
+			intV = int64(v)
+		case uint16:
+			valueType = "INTEGER"
+			intV = int64(v)
+		case uint32:
+			valueType = "INTEGER"
+			intV = int64(v)
+		case uint64:
+			// values above the range of int64 are kept as float
+			if v > math.MaxInt64 {
+				valueType = "FLOAT"
+				floatV = float64(v)
+			} else {
+				valueType = "INTEGER"
+				intV = int64(v)
+			}
 		case float32:
 			valueType = "FLOAT"
 			floatV = float64(v)
@@ -99,11 +271,27 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 			valueType = "FLOAT"
 			floatV = v
 		case []byte:
+			// SQLite returns BLOB values as bytes, which are formatted as text (or their length)
+			if column.BlobFormat == blobFormatLength {
+				valueType = "INTEGER"
+				intV = int64(len(v))
+				break
+			}
 			valueType = "STRING"
-			stringV = string(v)
+			var truncated bool
+			stringV, truncated = formatBlob(v, column.BlobFormat, maxBlobSize)
+			if truncated {
+				column.TruncatedValues++
+			}
 		case string:
 			valueType = "STRING"
 			stringV = v
+		case time.Time:
+			// the driver parses the values of DATE, DATETIME and TIMESTAMP columns. Other column
+			// types convert them like a string
+			valueType = "TIME"
+			timeV = v
+			stringV = parser.formatTime(v)
 		case nil:
 			valueType = "NULL"
 		default:
@@ -115,14 +303,10 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 			valueType = "UNKNOWN"
 		}
 
-		if column.Type == "UNKNOWN" && valueType != "NULL" {
-			// we need to decide on a type for the column as we need to
-			// fill a typed list later. Multiple types are not allowed
-			if valueType == "UNKNOWN" {
-				column.Type = "STRING"
-			} else {
-				column.Type = valueType
-			}
+		// a REAL value in an integer column turns it into a float column (unless the type is
+		// explicit), as truncating the value would lose information
+		if column.Type == "INTEGER" && valueType == "FLOAT" && !column.ExplicitType {
+			column.promoteToFloat()
 		}
 
 		// variable to indicate whether to explicitly set the value to null
@@ -131,25 +315,23 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 		if column.Type == "TIME" {
 			var value time.Time
 
-			if valueType == "INTEGER" {
-				value = time.Unix(intV, 0)
+			if valueType == "TIME" {
+				value = timeV
+			} else if valueType == "INTEGER" {
+				value = parser.parseInt(intV)
+			} else if valueType == "FLOAT" && (math.IsNaN(floatV) || math.IsInf(floatV, 0)) {
+				log.DefaultLogger.Warn("Could not parse value to timestamp", "value", floatV)
+				setNull = true
+				column.DroppedValues++
 			} else if valueType == "FLOAT" {
-				seconds, milliseconds := math.Modf(floatV)
-				value = time.Unix(int64(seconds), int64(milliseconds*1000000000))
+				value = parser.parseFloat(floatV)
 			} else if valueType != "NULL" {
 				val := fmt.Sprintf("%v", values[i])
-				value, err = time.Parse(time.RFC3339, val)
+				value, err = parser.parseString(val)
 				if err != nil {
-					// try parsing the string as a number
-					if f, err := strconv.ParseFloat(val, 64); err == nil {
-						seconds, milliseconds := math.Modf(f)
-						value = time.Unix(int64(seconds), int64(milliseconds*1000000000))
-					} else {
-						log.DefaultLogger.Warn(
-							"Could not parse (RFC3339) value to timestamp", "value", val,
-						)
-						setNull = true
-					}
+					log.DefaultLogger.Warn("Could not parse value to timestamp", "value", val)
+					setNull = true
+					column.DroppedValues++
 				}
 			}
 
@@ -168,12 +350,24 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 			case "INTEGER":
 				value = intV
 			case "FLOAT":
+				// NaN, infinite and values outside of the range of int64 have no integer value
+				if math.IsNaN(floatV) || floatV >= math.MaxInt64 || floatV < math.MinInt64 {
+					log.DefaultLogger.Debug("Could not convert value to int", "value", floatV)
+					setNull = true
+					column.DroppedValues++
+					break
+				}
 				value = int64(floatV)
+				if float64(value) != floatV {
+					column.CoercedValues++
+				}
+			case "NULL":
 			default:
 				value, err = strconv.ParseInt(string(stringV), 10, 64)
 				if err != nil {
 					log.DefaultLogger.Debug("Could not convert value to int", "value", stringV)
 					setNull = true
+					column.DroppedValues++
 				}
 			}
 
@@ -193,12 +387,14 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 				value = floatV
 			case "INTEGER":
 				value = float64(intV)
+			case "NULL":
 			default:
 				value, err = strconv.ParseFloat(string(stringV), 64)
 
 				if err != nil {
 					log.DefaultLogger.Debug("Could not convert value to float", "value", stringV)
 					setNull = true
+					column.DroppedValues++
 				}
 			}
 
@@ -217,7 +413,10 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 			case "INTEGER":
 				value = fmt.Sprintf("%d", intV)
 			case "FLOAT":
-				value = fmt.Sprintf("%f", floatV)
+				// the shortest representation, which keeps the exact value
+				value = strconv.FormatFloat(floatV, 'g', -1, 64)
+			case "STRING", "TIME":
+				value = stringV
 			default:
 				value = fmt.Sprintf("%v", values[i])
 			}
@@ -230,6 +429,65 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 			continue
 		}
 
+		if column.Type == "BOOL" {
+			var value bool
+
+			switch valueType {
+			case "INTEGER":
+				value = intV != 0
+			case "FLOAT":
+				value = floatV != 0
+			case "NULL":
+			default:
+				value, err = strconv.ParseBool(fmt.Sprintf("%v", values[i]))
+				if err != nil {
+					log.DefaultLogger.Debug("Could not convert value to bool", "value", values[i])
+					setNull = true
+					column.DroppedValues++
+				}
+			}
+
+			if setNull || valueType == "NULL" {
+				columns[i].BoolData = append(columns[i].BoolData, nil)
+			} else {
+				columns[i].BoolData = append(columns[i].BoolData, &value)
+			}
+			continue
+		}
+
+		if column.Type == "JSON" {
+			var value json.RawMessage
+
+			switch valueType {
+			case "INTEGER":
+				value = json.RawMessage(strconv.FormatInt(intV, 10))
+			case "FLOAT":
+				// JSON has no representation of NaN and infinite values
+				if math.IsNaN(floatV) || math.IsInf(floatV, 0) {
+					log.DefaultLogger.Debug("Could not convert value to JSON", "value", floatV)
+					setNull = true
+					column.DroppedValues++
+					break
+				}
+				value = json.RawMessage(strconv.FormatFloat(floatV, 'g', -1, 64))
+			case "NULL":
+			default:
+				value = json.RawMessage(stringV)
+				if !json.Valid(value) {
+					log.DefaultLogger.Debug("Could not convert value to JSON", "value", stringV)
+					setNull = true
+					column.DroppedValues++
+				}
+			}
+
+			if setNull || valueType == "NULL" {
+				columns[i].JSONData = append(columns[i].JSONData, nil)
+			} else {
+				columns[i].JSONData = append(columns[i].JSONData, &value)
+			}
+			continue
+		}
+
 		// column.Type == "UNKNOWN"
 		columns[i].TimeData = append(columns[i].TimeData, nil)
 		columns[i].IntData = append(columns[i].IntData, nil)
@@ -241,44 +499,16 @@ func addTransformedRow(rows *sql.Rows, columns []*sqlColumn) (err error) {
 	return nil
 }
 
+// fetchData executes the query on the connection and reads the columns of the result
 func fetchData(
-	config pluginConfig, queryConfig *queryConfigStruct, ctx context.Context,
+	conn *sql.Conn, queryConfig *queryConfigStruct, ctx context.Context,
 ) (columns []*sqlColumn, err error) {
-	db, err := sql.Open("sqlite", config.PathPrefix+config.Path+"?"+config.PathOptions)
-	if err != nil {
-		log.DefaultLogger.Error("Could not open database", "err", err)
-		return columns, err
-	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			log.DefaultLogger.Error("Error closing database", "err", err)
-		}
-	}()
-
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		log.DefaultLogger.Error("Could not get connection", "err", err)
-		return columns, err
-	}
-	defer func() {
-		if err := conn.Close(); err != nil {
-			log.DefaultLogger.Error("Error closing connection", "err", err)
-		}
-	}()
-
-	if config.AttachLimit != nil && os.Getenv("GF_PLUGIN_UNSAFE_ALLOW_ATTACH_LIMIT_ABOVE_ZERO") == "true" {
-		// https://www.sqlite.org/c3ref/c_limit_attached.html#sqlitelimitattached
-		// #define SQLITE_LIMIT_ATTACHED                  7
-		_, err = sqlite.Limit(conn, 7, int(*config.AttachLimit))
-	} else {
-		_, err = sqlite.Limit(conn, 7, 0)
-	}
+	parser, err := newTimeParser(queryConfig.TimeFormat, queryConfig.EpochUnit, queryConfig.Location)
 	if err != nil {
-		log.DefaultLogger.Error("Could not set attach limit", "err", err)
 		return columns, err
 	}
 
-	rows, err := conn.QueryContext(ctx, queryConfig.FinalQuery)
+	rows, err := conn.QueryContext(ctx, queryConfig.FinalQuery, queryConfig.Arguments...)
 	if err != nil {
 		log.DefaultLogger.Error(
 			"Could not execute query", "query", queryConfig.FinalQuery, "err", err,
@@ -305,15 +535,30 @@ func fetchData(
 	}
 
 	for idx := range columns {
-		columns[idx] = &sqlColumn{Name: columnTypes[idx].Name()}
+		columns[idx] = &sqlColumn{
+			Name: columnTypes[idx].Name(),
+			Raw:  slices.Contains(queryConfig.RawColumns, columnTypes[idx].Name()),
+		}
+		columns[idx].BlobFormat, err = queryConfig.blobFormat(columns[idx].Name)
+		if err != nil {
+			return columns, err
+		}
 
 		switch strings.ToUpper(columnTypes[idx].DatabaseTypeName()) {
 		case "INTEGER", "INT":
 			columns[idx].Type = "INTEGER"
 		case "REAL", "NUMERIC", "DOUBLE", "FLOAT":
 			columns[idx].Type = "FLOAT"
-		case "NULL", "TEXT", "BLOB":
+		case "BOOLEAN", "BOOL":
+			columns[idx].Type = "BOOL"
+		case "NULL", "TEXT":
 			columns[idx].Type = "STRING"
+		case "BLOB":
+			if columns[idx].BlobFormat == blobFormatLength {
+				columns[idx].Type = "INTEGER"
+			} else {
+				columns[idx].Type = "STRING"
+			}
 		default:
 			log.DefaultLogger.Debug(
 				"Unknown database type",
@@ -328,19 +573,48 @@ func fetchData(
 		for _, timeColumnName := range queryConfig.TimeColumns {
 			if columns[idx].Name == timeColumnName {
 				columns[idx].Type = "TIME"
-				if queryConfig.FillValuesTimeColumnIndex == -1 {
-					queryConfig.FillValuesTimeColumnIndex = idx
-				}
 				break
 			}
 		}
+
+		// SQLite stores booleans as integers. Computed columns (e.g. comparisons) have no declared
+		// type and are only converted if they are listed
+		if slices.Contains(queryConfig.BoolColumns, columns[idx].Name) {
+			columns[idx].Type = "BOOL"
+		}
+
+		// explicit column types take precedence over the detected type
+		if override, exists := queryConfig.ColumnTypes[columns[idx].Name]; exists {
+			columnType, supported := columnTypeOverrides[strings.ToLower(override)]
+			if !supported {
+				return columns, fmt.Errorf(
+					"unsupported type '%s' for the column %s", override, columns[idx].Name,
+				)
+			}
+			columns[idx].Type = columnType
+			columns[idx].ExplicitType = true
+		}
 	}
 
+	// the rows are buffered to infer the types of columns without a type from all values
+	rowValues := [][]interface{}{}
 	for rows.Next() {
-		err := addTransformedRow(rows, columns)
+		if queryConfig.MaxRows > 0 && len(rowValues) >= queryConfig.MaxRows {
+			log.DefaultLogger.Debug("Stopped reading rows after reaching the limit", "limit", queryConfig.MaxRows)
+			queryConfig.Notices = append(queryConfig.Notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text: fmt.Sprintf(
+					"The result was truncated as it exceeded the limit of %d rows", queryConfig.MaxRows,
+				),
+			})
+			break
+		}
+
+		values, err := scanRow(rows, columnCount)
 		if err != nil {
 			return columns, err
 		}
+		rowValues = append(rowValues, values)
 	}
 
 	err = rows.Err()
@@ -349,15 +623,100 @@ func fetchData(
 		return columns, err
 	}
 
+	// time series use the text columns as labels, so only tables get additional time columns
+	inferTimes := queryConfig.QueryType == tableType || queryConfig.QueryType == ""
+	inferColumnTypes(columns, rowValues, parser, inferTimes)
+	// gaps are only filled in a configured time column, not in columns inferred to be times
+	for idx, column := range columns {
+		if column.Type == "TIME" && queryConfig.FillValuesTimeColumnIndex == -1 &&
+			slices.Contains(queryConfig.TimeColumns, column.Name) {
+			queryConfig.FillValuesTimeColumnIndex = idx
+		}
+	}
+
+	for _, values := range rowValues {
+		err := addTransformedRow(values, columns, parser, queryConfig.MaxBlobSize)
+		if err != nil {
+			return columns, err
+		}
+	}
+	queryConfig.Notices = append(queryConfig.Notices, conversionNotices(columns)...)
+	queryConfig.Notices = append(queryConfig.Notices, blobNotices(columns, queryConfig.MaxBlobSize)...)
+
 	return columns, nil
 }
 
 type queryModel struct {
 	QueryText   string   `json:"queryText"`
 	TimeColumns []string `json:"timeColumns"`
+	// BoolColumns are converted to booleans (in addition to columns declared as BOOLEAN)
+	BoolColumns []string `json:"boolColumns"`
+
+	// EditorMode is either code (the default) or builder. In the builder mode the query text
+	// is compiled from the Builder model
+	EditorMode string        `json:"editorMode"`
+	Builder    *builderQuery `json:"builder"`
+	// builderArguments are bound to the parameters of the compiled builder query
+	builderArguments []interface{}
+
+	// ColumnTypes overrides the detected type of columns. The supported types are
+	// time, int, float, string, bool and json
+	ColumnTypes map[string]string `json:"columnTypes"`
+
+	// TimeFormat and EpochUnit override the time parsing settings of the datasource
+	TimeFormat string `json:"timeFormat"`
+	EpochUnit  string `json:"epochUnit"`
+
+	// EdgesQueryText is the query of the edges of a node graph query (optional)
+	EdgesQueryText string `json:"edgesQueryText"`
+
+	// LogContext selects the log lines around a log line of a logs query
+	LogContext *logContextQuery `json:"logContext"`
+
+	// ExpandJSONColumns flattens the JSON objects of the columns into one column per (dotted)
+	// path. The value is the depth of flattened nested objects (0 means no limit)
+	ExpandJSONColumns map[string]int `json:"expandJsonColumns"`
+
+	// BlobFormats are the formats of BLOB values by column name: hex, base64, length or utf8
+	// (the default, which falls back to hex for binary values)
+	BlobFormats map[string]string `json:"blobFormats"`
+
+	// QueryTimeout (in seconds) and MaxRows can only lower the limits of the datasource
+	QueryTimeout int `json:"queryTimeout"`
+	MaxRows      int `json:"maxRows"`
 }
 
-func query(dataQuery backend.DataQuery, config pluginConfig, ctx context.Context) (response backend.DataResponse) {
+// newQueryConfig creates the configuration to execute the query of the datasource
+func newQueryConfig(
+	qm queryModel, dataQuery backend.DataQuery, config pluginConfig,
+) (queryConfigStruct, error) {
+	location, err := config.location()
+	if err != nil {
+		return queryConfigStruct{}, err
+	}
+
+	return queryConfigStruct{
+		BaseQuery:                 qm.QueryText,
+		FinalQuery:                qm.QueryText,
+		Arguments:                 qm.builderArguments,
+		TimeColumns:               qm.TimeColumns,
+		BoolColumns:               qm.BoolColumns,
+		ColumnTypes:               qm.ColumnTypes,
+		TimeFormat:                cmp.Or(qm.TimeFormat, config.TimeFormat),
+		EpochUnit:                 cmp.Or(qm.EpochUnit, config.EpochUnit),
+		QueryType:                 dataQuery.QueryType,
+		TimeRange:                 dataQuery.TimeRange,
+		FillValuesTimeColumnIndex: -1,
+		Location:                  location,
+		BlobFormats:               qm.BlobFormats,
+		MaxBlobSize:               config.maxBlobSize(),
+		MaxRows:                   stricterLimit(config.MaxRows, qm.MaxRows),
+	}, nil
+}
+
+func query(
+	dataQuery backend.DataQuery, config pluginConfig, db *sql.DB, ctx context.Context,
+) (response backend.DataResponse) {
 	// Check if the database path is blocked by the GF_PLUGIN_BLOCK_LIST
 	// This check is performed here in addition to the health check because:
 	// - Health checks do not prevent saving a datasource configuration in Grafana
@@ -376,78 +735,176 @@ func query(dataQuery backend.DataQuery, config pluginConfig, ctx context.Context
 		return response
 	}
 
-	queryConfig := queryConfigStruct{
-		BaseQuery:                 qm.QueryText,
-		FinalQuery:                qm.QueryText,
-		TimeColumns:               qm.TimeColumns,
-		QueryType:                 dataQuery.QueryType,
-		FillValuesTimeColumnIndex: -1,
+	err = qm.applyBuilder()
+	if err != nil {
+		response.Error = err
+		return response
 	}
 
-	err = replaceVariables(&queryConfig, dataQuery)
+	queryConfig, err := newQueryConfig(qm, dataQuery, config)
 	if err != nil {
 		response.Error = err
 		return response
 	}
-	log.DefaultLogger.Debug("Variables replaced")
+	// the query types with a special format need columns of a certain type
+	switch queryConfig.QueryType {
+	case annotationType:
+		prepareAnnotationQuery(&queryConfig)
+	case logsType, logsVolumeType:
+		prepareLogsQuery(&queryConfig)
+	case tracesType:
+		prepareTracesQuery(&queryConfig)
+	case nodeGraphType:
+		prepareNodeGraphQuery(&queryConfig)
+	}
+
+	if timeout := stricterLimit(config.QueryTimeout, qm.QueryTimeout); timeout > 0 {
+		// the SQLite driver interrupts the running statement when the context is done
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(timeout)*time.Second, errQueryTimeout)
+		defer cancel()
+	}
+
+	// the edges query shares the settings of the query but not its state (e.g. the notices)
+	edgesConfig := newEdgesQueryConfig(queryConfig, qm.EdgesQueryText)
 
-	err = applyMacros(&queryConfig)
+	err = replaceVariables(&queryConfig, dataQuery)
 	if err != nil {
 		response.Error = err
 		return response
 	}
+	log.DefaultLogger.Debug("Variables replaced")
+
+	// each statement of the query returns its own frames
+	statements := splitStatements(queryConfig.FinalQuery)
+	switch queryConfig.QueryType {
+	case annotationType, logsType, logsVolumeType, tracesType, nodeGraphType:
+		if len(statements) > 1 {
+			response.Error = fmt.Errorf(
+				"queries of the type '%s' cannot contain multiple statements", queryConfig.QueryType,
+			)
+			return response
+		}
+	}
+
+	// the macros of all statements are applied before the database is queried
+	statementConfigs := make([]queryConfigStruct, len(statements))
+	for idx, statement := range statements {
+		statementConfigs[idx] = queryConfig
+		statementConfigs[idx].FinalQuery = statement.Query
+
+		err = applyMacros(&statementConfigs[idx])
+		if err != nil {
+			response.Error = err
+			return response
+		}
+	}
 	log.DefaultLogger.Debug("Macros applied")
 
-	columns, err := fetchData(config, &queryConfig, ctx)
+	// the statements share a connection, so that later statements see the state created by
+	// earlier ones (e.g. attached databases or temporary tables)
+	conn, err := queryConn(config, db, ctx)
 	if err != nil {
 		response.Error = err
 		return response
 	}
+	defer releaseQueryConn(conn, queryConfig.FinalQuery+"\n"+edgesConfig.FinalQuery)
+
+	for idx, statement := range statements {
+		frames, err := queryStatement(qm, &statementConfigs[idx], &edgesConfig, dataQuery, conn, ctx)
+		if err != nil {
+			// other deadlines (e.g. of the request) are not the timeout of the query
+			if errors.Is(context.Cause(ctx), errQueryTimeout) {
+				err = fmt.Errorf("the query did not finish within the timeout: %w", err)
+			}
+			response.Error = err
+			return response
+		}
+
+		for _, frame := range frames {
+			// statements without a result (e.g. setting a PRAGMA) are skipped
+			if len(statements) > 1 && len(frame.Fields) == 0 {
+				continue
+			}
+			if statement.Name != "" {
+				frame.Name = statement.Name
+			}
+			response.Frames = append(response.Frames, frame)
+		}
+	}
+
+	return response
+}
+
+// queryStatement executes a single statement of the query (with applied macros) and converts the
+// result to frames
+func queryStatement(
+	qm queryModel,
+	queryConfig *queryConfigStruct,
+	edgesConfig *queryConfigStruct,
+	dataQuery backend.DataQuery,
+	conn *sql.Conn,
+	ctx context.Context,
+) ([]*data.Frame, error) {
+	columns, err := fetchData(conn, queryConfig, ctx)
+	if err != nil {
+		return nil, err
+	}
 	log.DefaultLogger.Debug("Fetched data from database")
 
-	frame := data.NewFrame("")
-	frame.Meta = &data.FrameMeta{ExecutedQueryString: queryConfig.FinalQuery}
+	var edgeColumns []*sqlColumn
+	if queryConfig.QueryType == nodeGraphType && strings.TrimSpace(qm.EdgesQueryText) != "" {
+		edgeColumns, err = fetchEdges(conn, edgesConfig, dataQuery, ctx)
+		if err != nil {
+			return nil, err
+		}
+		log.DefaultLogger.Debug("Fetched edges from database")
+	}
 
 	if queryConfig.ShouldFillValues {
-		err := fillGaps(columns, &queryConfig)
+		err := fillGaps(columns, queryConfig)
 		if err != nil {
-			response.Error = err
-			return response
+			return nil, err
 		}
 		log.DefaultLogger.Debug("Filled gaps in data according to macro")
 	}
 
-	// construct a regular SQL dataframe (for time series this is usually the "long format")
-	for _, column := range columns {
-		switch column.Type {
-		case "TIME":
-			frame.Fields = append(
-				frame.Fields, data.NewField(column.Name, nil, column.TimeData),
-			)
-		case "FLOAT":
-			frame.Fields = append(
-				frame.Fields, data.NewField(column.Name, nil, column.FloatData),
-			)
-		case "INTEGER":
-			frame.Fields = append(
-				frame.Fields, data.NewField(column.Name, nil, column.IntData),
-			)
-		case "STRING":
-			frame.Fields = append(
-				frame.Fields, data.NewField(column.Name, nil, column.StringData),
-			)
-		default:
-			frame.Fields = append(
-				frame.Fields, data.NewField(column.Name, nil, column.FloatData),
-			)
+	columns, err = expandJSONColumns(columns, qm.ExpandJSONColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	switch queryConfig.QueryType {
+	case annotationType:
+		err := toAnnotationColumns(columns)
+		if err != nil {
+			return nil, err
+		}
+	case logsType:
+		frame, err := logsFrame(columns, queryConfig, qm.LogContext)
+		if err != nil {
+			return nil, err
 		}
+		return []*data.Frame{frame}, nil
+	case tracesType:
+		frame, err := tracesFrame(columns, queryConfig)
+		if err != nil {
+			return nil, err
+		}
+		return []*data.Frame{frame}, nil
+	case logsVolumeType:
+		return logsVolumeFrames(columns, queryConfig, dataQuery)
+	case nodeGraphType:
+		return nodeGraphFrames(columns, queryConfig, edgeColumns, edgesConfig)
 	}
 
+	// construct a regular SQL dataframe (for time series this is usually the "long format")
+	frame := columnsToFrame(columns)
+	frame.Meta = queryConfig.frameMeta()
+
 	// default case. Return whatever SQL we received
 	if queryConfig.isTableType() {
-		response.Frames = append(response.Frames, frame)
-
-		return response
+		return []*data.Frame{frame}, nil
 	}
 	// as the QueryType currently only has two options no further "if check" is required
 
@@ -455,10 +912,9 @@ func query(dataQuery backend.DataQuery, config pluginConfig, ctx context.Context
 		frame, err = mockableLongToWide(frame, nil)
 		if err != nil {
 			log.DefaultLogger.Error("Could not convert from long to wide time-series", "err", err)
-			response.Error = err
-			return response
+			return nil, err
 		}
-		frame.Meta = &data.FrameMeta{ExecutedQueryString: queryConfig.FinalQuery}
+		frame.Meta = queryConfig.frameMeta()
 
 		log.DefaultLogger.Debug("Initial data converted into wide time-series")
 
@@ -487,6 +943,7 @@ func query(dataQuery backend.DataQuery, config pluginConfig, ctx context.Context
 	// some plugins do not play well with the "wide format" of a time series
 	// therefore we transform into individual frames
 	// https://github.com/fr-ser/grafana-sqlite-datasource/issues/16
+	frames := []*data.Frame{}
 	tsSchema := frame.TimeSeriesSchema()
 	for idx, field := range frame.Fields {
 		if idx == tsSchema.TimeIndex {
@@ -496,13 +953,51 @@ func query(dataQuery backend.DataQuery, config pluginConfig, ctx context.Context
 			frame.Fields[tsSchema.TimeIndex],
 			field,
 		)
-		partialFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryConfig.FinalQuery}
+		partialFrame.Meta = queryConfig.frameMeta()
 
-		response.Frames = append(response.Frames, partialFrame)
+		frames = append(frames, partialFrame)
 	}
 	log.DefaultLogger.Debug("Wide time-series converted into multiple frames")
 
-	return response
+	return frames, nil
+}
+
+// columnsToFrame creates a data frame with one field per column
+func columnsToFrame(columns []*sqlColumn) *data.Frame {
+	frame := data.NewFrame("")
+	for _, column := range columns {
+		switch column.Type {
+		case "TIME":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.TimeData),
+			)
+		case "FLOAT":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.FloatData),
+			)
+		case "INTEGER":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.IntData),
+			)
+		case "STRING":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.StringData),
+			)
+		case "BOOL":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.BoolData),
+			)
+		case "JSON":
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.JSONData),
+			)
+		default:
+			frame.Fields = append(
+				frame.Fields, data.NewField(column.Name, nil, column.FloatData),
+			)
+		}
+	}
+	return frame
 }
 
 func fieldHasOnlyNulls(field *data.Field) bool {
@@ -0,0 +1,265 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// builderEditorMode is the editor mode of queries, which are compiled from the structured
+// builder model instead of using the query text
+const builderEditorMode = "builder"
+
+// the supported formats of the time column of the builder, which determine the time filter
+const (
+	builderTimeFormatISO    = "iso"
+	builderTimeFormatUnix   = "unix"
+	builderTimeFormatUnixMs = "unixMs"
+)
+
+var builderAggregations = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+}
+
+var builderOperators = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"LIKE": true, "NOT LIKE": true, "IN": true, "NOT IN": true,
+	"IS NULL": true, "IS NOT NULL": true,
+}
+
+// builderQuery is the structured query of the visual query builder
+type builderQuery struct {
+	Table   string          `json:"table"`
+	Columns []builderColumn `json:"columns"`
+	Filters []builderFilter `json:"filters"`
+	GroupBy []string        `json:"groupBy"`
+	OrderBy []builderOrder  `json:"orderBy"`
+	Limit   int             `json:"limit"`
+
+	// TimeColumn is selected as first column, formatted as time and filtered by the time range
+	TimeColumn string `json:"timeColumn"`
+	// TimeFormat is the storage format of the time column (iso, unix or unixMs)
+	TimeFormat string `json:"timeFormat"`
+}
+
+type builderColumn struct {
+	Name        string `json:"name"`
+	Aggregation string `json:"aggregation"`
+	Alias       string `json:"alias"`
+}
+
+type builderFilter struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator"`
+	// Value is a string, number, boolean or null. The IN operators expect a list of values
+	Value interface{} `json:"value"`
+}
+
+type builderOrder struct {
+	Column    string `json:"column"`
+	Direction string `json:"direction"`
+}
+
+// applyBuilder replaces the query text with the compiled query of the builder
+func (qm *queryModel) applyBuilder() error {
+	if qm.EditorMode != builderEditorMode {
+		return nil
+	}
+	if qm.Builder == nil {
+		return fmt.Errorf("the query builder has no query")
+	}
+
+	queryText, arguments, err := qm.Builder.compile()
+	if err != nil {
+		return fmt.Errorf("could not build the query: %w", err)
+	}
+	qm.QueryText = queryText
+	qm.builderArguments = arguments
+	if qm.Builder.TimeColumn != "" {
+		qm.TimeColumns = append(qm.TimeColumns, qm.Builder.TimeColumn)
+	}
+
+	return nil
+}
+
+// compile creates the SQL statement of the builder query and the arguments of its parameters.
+// All identifiers are quoted and all values are bound to parameters, so that they are not changed
+// by the variables and macros. Aggregations, operators and directions are only accepted from a
+// fixed list
+func (bq *builderQuery) compile() (string, []interface{}, error) {
+	if bq.Table == "" {
+		return "", nil, fmt.Errorf("no table selected")
+	}
+
+	selects := []string{}
+	if bq.TimeColumn != "" {
+		selects = append(selects, quoteIdentifier(bq.TimeColumn))
+	}
+	for _, column := range bq.Columns {
+		expression, err := column.compile()
+		if err != nil {
+			return "", nil, err
+		}
+		selects = append(selects, expression)
+	}
+	if len(selects) == 0 {
+		selects = append(selects, "*")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), quoteIdentifier(bq.Table))
+
+	conditions := []string{}
+	if bq.TimeColumn != "" {
+		timeFilter, err := bq.timeFilter()
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, timeFilter)
+	}
+	arguments := []interface{}{}
+	for _, filter := range bq.Filters {
+		condition, filterArguments, err := filter.compile()
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+		arguments = append(arguments, filterArguments...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if len(bq.GroupBy) > 0 {
+		groups := make([]string, len(bq.GroupBy))
+		for idx, column := range bq.GroupBy {
+			groups[idx] = quoteIdentifier(column)
+		}
+		query += " GROUP BY " + strings.Join(groups, ", ")
+	}
+
+	orders := []string{}
+	for _, order := range bq.OrderBy {
+		direction := strings.ToUpper(order.Direction)
+		if direction == "" {
+			direction = "ASC"
+		}
+		if direction != "ASC" && direction != "DESC" {
+			return "", nil, fmt.Errorf("unsupported order direction: %s", order.Direction)
+		}
+		orders = append(orders, quoteIdentifier(order.Column)+" "+direction)
+	}
+	// time series need to be sorted by time
+	if len(orders) == 0 && bq.TimeColumn != "" {
+		orders = append(orders, quoteIdentifier(bq.TimeColumn)+" ASC")
+	}
+	if len(orders) > 0 {
+		query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+
+	if bq.Limit < 0 {
+		return "", nil, fmt.Errorf("the limit must not be negative: %d", bq.Limit)
+	}
+	if bq.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", bq.Limit)
+	}
+
+	return query, arguments, nil
+}
+
+// timeFilter filters the time column by the time range of the query with the time macros
+func (bq *builderQuery) timeFilter() (string, error) {
+	switch bq.TimeFormat {
+	case builderTimeFormatISO, "":
+		return fmt.Sprintf("$__timeFilter(%s)", quoteIdentifier(bq.TimeColumn)), nil
+	case builderTimeFormatUnix:
+		return fmt.Sprintf("$__unixEpochFilter(%s)", quoteIdentifier(bq.TimeColumn)), nil
+	case builderTimeFormatUnixMs:
+		return fmt.Sprintf("$__unixEpochMsFilter(%s)", quoteIdentifier(bq.TimeColumn)), nil
+	default:
+		return "", fmt.Errorf("unsupported time format: %s", bq.TimeFormat)
+	}
+}
+
+func (column builderColumn) compile() (string, error) {
+	if column.Name == "" {
+		return "", fmt.Errorf("a selected column has no name")
+	}
+
+	expression := quoteIdentifier(column.Name)
+	if column.Name == "*" {
+		expression = "*"
+	}
+
+	if column.Aggregation != "" {
+		aggregation := strings.ToLower(column.Aggregation)
+		if !builderAggregations[aggregation] {
+			return "", fmt.Errorf("unsupported aggregation: %s", column.Aggregation)
+		}
+		expression = fmt.Sprintf("%s(%s)", aggregation, expression)
+	} else if column.Name == "*" {
+		return "", fmt.Errorf("all columns (*) can only be selected with an aggregation")
+	}
+
+	if column.Alias != "" {
+		expression += " AS " + quoteIdentifier(column.Alias)
+	}
+	return expression, nil
+}
+
+func (filter builderFilter) compile() (string, []interface{}, error) {
+	operator := strings.ToUpper(filter.Operator)
+	if !builderOperators[operator] {
+		return "", nil, fmt.Errorf("unsupported filter operator: %s", filter.Operator)
+	}
+	column := quoteIdentifier(filter.Column)
+
+	switch operator {
+	case "IS NULL", "IS NOT NULL":
+		return column + " " + operator, nil, nil
+	case "IN", "NOT IN":
+		values, isList := filter.Value.([]interface{})
+		if !isList || len(values) == 0 {
+			return "", nil, fmt.Errorf("the operator %s needs a list of values", operator)
+		}
+		arguments := make([]interface{}, len(values))
+		for idx, value := range values {
+			argument, err := sqlArgument(value)
+			if err != nil {
+				return "", nil, err
+			}
+			arguments[idx] = argument
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("%s %s (%s)", column, operator, placeholders), arguments, nil
+	default:
+		argument, err := sqlArgument(filter.Value)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s %s ?", column, operator), []interface{}{argument}, nil
+	}
+}
+
+// sqlArgument converts a JSON value to the argument of a parameter. Booleans are integers (like
+// in SQLite) and whole numbers are integers, so that they match the values of text columns
+func sqlArgument(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string:
+		return v, nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("unsupported filter value: %v", v)
+		}
+		if v == math.Trunc(v) && math.Abs(v) < maxExactFloatInteger {
+			return int64(v), nil
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported filter value: %v", v)
+	}
+}
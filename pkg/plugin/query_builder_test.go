@@ -0,0 +1,212 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestBuilderQueries(t *testing.T) {
+	for _, testCase := range []struct {
+		name              string
+		builder           string
+		expected          string
+		expectedArguments []interface{}
+	}{
+		{
+			name:     "all columns",
+			builder:  `{"table": "readings"}`,
+			expected: `SELECT * FROM "readings"`,
+		},
+		{
+			name: "columns with aggregations",
+			builder: `{
+				"table": "readings",
+				"columns": [
+					{"name": "sensor"},
+					{"name": "value", "aggregation": "AVG", "alias": "average"},
+					{"name": "*", "aggregation": "count"}
+				],
+				"groupBy": ["sensor"],
+				"orderBy": [{"column": "average", "direction": "desc"}],
+				"limit": 10
+			}`,
+			expected: `SELECT "sensor", avg("value") AS "average", count(*) FROM "readings" ` +
+				`GROUP BY "sensor" ORDER BY "average" DESC LIMIT 10`,
+		},
+		{
+			name: "filters",
+			builder: `{
+				"table": "readings",
+				"filters": [
+					{"column": "name", "operator": "=", "value": "it's"},
+					{"column": "value", "operator": ">=", "value": 1.5},
+					{"column": "active", "operator": "!=", "value": true},
+					{"column": "sensor", "operator": "not in", "value": [1, "two"]},
+					{"column": "deleted", "operator": "IS NULL"}
+				]
+			}`,
+			expected: `SELECT * FROM "readings" WHERE "name" = ? AND "value" >= ? ` +
+				`AND "active" != ? AND "sensor" NOT IN (?, ?) AND "deleted" IS NULL`,
+			expectedArguments: []interface{}{"it's", 1.5, int64(1), int64(1), "two"},
+		},
+		{
+			name: "values with macros",
+			builder: `{
+				"table": "readings",
+				"filters": [{"column": "name", "operator": "=", "value": "$__timeFilter(x) $__from"}]
+			}`,
+			expected:          `SELECT * FROM "readings" WHERE "name" = ?`,
+			expectedArguments: []interface{}{"$__timeFilter(x) $__from"},
+		},
+		{
+			name:    "time column",
+			builder: `{"table": "readings", "columns": [{"name": "value"}], "timeColumn": "ts", "timeFormat": "unix"}`,
+			expected: `SELECT "ts", "value" FROM "readings" WHERE $__unixEpochFilter("ts") ` +
+				`ORDER BY "ts" ASC`,
+		},
+		{
+			name:     "quoted identifiers",
+			builder:  `{"table": "my \"table\"", "columns": [{"name": "a\"; DROP TABLE x; --"}]}`,
+			expected: `SELECT "a""; DROP TABLE x; --" FROM "my ""table"""`,
+		},
+	} {
+		var builder builderQuery
+		if err := json.Unmarshal([]byte(testCase.builder), &builder); err != nil {
+			t.Fatalf("Invalid test case %s - %s", testCase.name, err)
+		}
+
+		query, arguments, err := builder.compile()
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", testCase.name, err)
+			continue
+		}
+		if query != testCase.expected {
+			t.Errorf(
+				"Unexpected query for %s. Expected: %s. Got: %s", testCase.name, testCase.expected, query,
+			)
+		}
+		if diff := cmp.Diff(testCase.expectedArguments, arguments, cmpopts.EquateEmpty()); diff != "" {
+			t.Errorf("Unexpected arguments for %s: %s", testCase.name, diff)
+		}
+	}
+}
+
+func TestBuilderQueriesShouldRejectInvalidInput(t *testing.T) {
+	for _, testCase := range []struct {
+		builder  string
+		expected string
+	}{
+		{builder: `{}`, expected: "no table selected"},
+		{
+			builder:  `{"table": "t", "columns": [{"name": "a", "aggregation": "sum(1)); --"}]}`,
+			expected: "unsupported aggregation",
+		},
+		{
+			builder:  `{"table": "t", "filters": [{"column": "a", "operator": "= 1 OR 1 ="}]}`,
+			expected: "unsupported filter operator",
+		},
+		{
+			builder:  `{"table": "t", "filters": [{"column": "a", "operator": "IN", "value": 1}]}`,
+			expected: "needs a list of values",
+		},
+		{
+			builder:  `{"table": "t", "filters": [{"column": "a", "operator": "=", "value": {"a": 1}}]}`,
+			expected: "unsupported filter value",
+		},
+		{
+			builder:  `{"table": "t", "orderBy": [{"column": "a", "direction": "sideways"}]}`,
+			expected: "unsupported order direction",
+		},
+		{builder: `{"table": "t", "columns": [{"name": "*"}]}`, expected: "only be selected with an aggregation"},
+		{builder: `{"table": "t", "timeColumn": "ts", "timeFormat": "rfc"}`, expected: "unsupported time format"},
+		{builder: `{"table": "t", "limit": -1}`, expected: "must not be negative"},
+	} {
+		var builder builderQuery
+		if err := json.Unmarshal([]byte(testCase.builder), &builder); err != nil {
+			t.Fatalf("Invalid test case %s - %s", testCase.builder, err)
+		}
+
+		_, _, err := builder.compile()
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Errorf("Expected error '%s' for %s but got: %v", testCase.expected, testCase.builder, err)
+		}
+	}
+}
+
+func TestBuilderQueryShouldBeExecuted(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE readings(ts INTEGER, sensor TEXT, value REAL);
+		INSERT INTO readings(ts, sensor, value)
+		VALUES (10, 'a', 1.0), (20, 'a', 3.0), (30, 'b', 5.0), (5000, 'a', 7.0);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		EditorMode: builderEditorMode,
+		Builder: &builderQuery{
+			Table:      "readings",
+			Columns:    []builderColumn{{Name: "value"}},
+			Filters:    []builderFilter{{Column: "sensor", Operator: "=", Value: "a"}},
+			TimeColumn: "ts",
+			TimeFormat: builderTimeFormatUnix,
+		},
+	})
+	dataQuery.TimeRange = backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(100, 0)}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("ts", nil, []*time.Time{unixTimePointer(10), unixTimePointer(20)}),
+		data.NewField("value", nil, []*float64{floatPointer(1), floatPointer(3)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: `SELECT "ts", "value" FROM "readings" WHERE "ts" BETWEEN 0 AND 100 ` +
+			`AND "sensor" = ? ORDER BY "ts" ASC`,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestBuilderQueryShouldNotReplaceMacrosInValues(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE readings(sensor TEXT, value INTEGER);
+		INSERT INTO readings(sensor, value) VALUES ('$__from', 1), ('a', 2), ('5', 3);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		EditorMode: builderEditorMode,
+		Builder: &builderQuery{
+			Table:   "readings",
+			Columns: []builderColumn{{Name: "value"}},
+			Filters: []builderFilter{
+				{Column: "sensor", Operator: "IN", Value: []interface{}{"$__from", 5.0}},
+			},
+			OrderBy: []builderOrder{{Column: "value"}},
+		},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedField := data.NewField("value", nil, []*int64{intPointer(1), intPointer(3)})
+	if diff := cmp.Diff(expectedField, response.Frames[0].Fields[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
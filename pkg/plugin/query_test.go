@@ -29,6 +29,25 @@ func createTmpDB(seedSQL string) (dbPath string, cleanup func()) {
 	return dbPath, cleanup
 }
 
+// getDatasource creates a datasource with its own connection pool.
+// The pool is closed at the end of the test
+func getDatasource(t *testing.T, config pluginConfig) *sqliteDatasource {
+	db, err := openDB(config)
+	if err != nil {
+		t.Fatalf("Could not open database - %s", err)
+	}
+	ds := &sqliteDatasource{pluginConfig: config, db: db}
+	t.Cleanup(ds.Dispose)
+
+	return ds
+}
+
+// runQuery executes a single query with a new datasource created from the config
+func runQuery(t *testing.T, dataQuery backend.DataQuery, config pluginConfig) backend.DataResponse {
+	ds := getDatasource(t, config)
+	return query(dataQuery, ds.pluginConfig, ds.db, context.Background())
+}
+
 func getDataQuery(targetModel queryModel) backend.DataQuery {
 	jsonData, _ := json.Marshal(targetModel)
 	return backend.DataQuery{JSON: jsonData}
@@ -46,6 +65,10 @@ func intPointer(x int64) *int64 {
 	return &x
 }
 
+func boolPointer(x bool) *bool {
+	return &x
+}
+
 func timePointer(x time.Time) *time.Time {
 	return &x
 }
@@ -61,7 +84,7 @@ func TestQueryWithoutRows(t *testing.T) {
 	queryText := "-- not a query"
 	dataQuery := getDataQuery(queryModel{QueryText: queryText})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -87,7 +110,7 @@ func TestEmptyQuery(t *testing.T) {
 	queryText := "SELECT 1 as foo WHERE false"
 	dataQuery := getDataQuery(queryModel{QueryText: queryText})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -114,7 +137,7 @@ func TestLimitAttached(t *testing.T) {
 
 	dataQuery := getDataQuery(queryModel{QueryText: "ATTACH DATABASE ':memory:' AS test_name; SELECT 1"})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath, AttachLimit: intPointer(0)}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, AttachLimit: intPointer(0)})
 	if response.Error == nil {
 		t.Errorf("Expected error but got nothing. Response: %+v", response)
 	}
@@ -130,7 +153,7 @@ func TestNoResultsTable(t *testing.T) {
 
 	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test WHERE false"})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -170,7 +193,7 @@ func TestNoResultsTimeSeriesWithUnknownColumns(t *testing.T) {
 	})
 	dataQuery.QueryType = "time series"
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -205,7 +228,7 @@ func TestInvalidQuery(t *testing.T) {
 
 	dataQuery := backend.DataQuery{JSON: []byte(`not even json`)}
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error == nil {
 		t.Errorf("Expected unmarshal error but got nothing. Response: %+v", response)
 	}
@@ -219,7 +242,7 @@ func TestReplaceToAndFromVariables(t *testing.T) {
 	dataQuery.TimeRange.From = time.Unix(123, 0)
 	dataQuery.TimeRange.To = time.Unix(456, 0)
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -242,6 +265,62 @@ func TestReplaceToAndFromVariables(t *testing.T) {
 	}
 }
 
+func TestReplaceIntervalVariables(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT '$__interval' AS a, $__interval_ms AS b",
+	})
+	dataQuery.Interval = 5 * time.Minute
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Errorf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("a", nil, []*string{strPointer("5m")}),
+		data.NewField("b", nil, []*int64{intPointer(300000)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT '5m' AS a, 300000 AS b"}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestIntervalShouldBeDerivedFromMaxDataPoints(t *testing.T) {
+	queryConfig := queryConfigStruct{FinalQuery: "$__interval $__interval_ms"}
+	dataQuery := backend.DataQuery{
+		MaxDataPoints: 100,
+		TimeRange:     backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(6000, 0)},
+	}
+
+	err := replaceVariables(&queryConfig, dataQuery)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	if queryConfig.FinalQuery != "1m 60000" {
+		t.Errorf("Unexpected query: %s", queryConfig.FinalQuery)
+	}
+}
+
+func TestIntervalVariablesShouldBeKeptWithoutAnInterval(t *testing.T) {
+	queryConfig := queryConfigStruct{FinalQuery: "$__interval $__interval_ms"}
+
+	err := replaceVariables(&queryConfig, backend.DataQuery{})
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	if queryConfig.FinalQuery != "$__interval $__interval_ms" {
+		t.Errorf("Unexpected query: %s", queryConfig.FinalQuery)
+	}
+}
+
 func TestQueryShouldFailWhenPathIsBlocked(t *testing.T) {
 	dbPath, cleanup := createTmpDB(`SELECT 1`)
 	defer cleanup()
@@ -258,7 +337,7 @@ func TestQueryShouldFailWhenPathIsBlocked(t *testing.T) {
 	_ = os.Setenv("GF_PLUGIN_BLOCK_LIST", dbPath)
 
 	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1"})
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 
 	if response.Error == nil {
 		t.Errorf("Expected error but got none")
@@ -268,3 +347,137 @@ func TestQueryShouldFailWhenPathIsBlocked(t *testing.T) {
 		t.Errorf("Unexpected error message: %s", response.Error.Error())
 	}
 }
+
+func TestMaxRowsShouldTruncateTheResult(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(value INTEGER);
+		INSERT INTO test(value) VALUES (1), (2), (3);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test"})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, MaxRows: 2})
+	if response.Error != nil {
+		t.Errorf("Unexpected error - %s", response.Error)
+	}
+
+	if len(response.Frames) != 1 {
+		t.Fatalf(
+			"Expected one frame but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	expectedFrame := data.NewFrame(
+		"", data.NewField("value", nil, []*int64{intPointer(1), intPointer(2)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: "SELECT * FROM test",
+		Notices: []data.Notice{{
+			Severity: data.NoticeSeverityWarning,
+			Text:     "The result was truncated as it exceeded the limit of 2 rows",
+		}},
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestMaxRowsShouldNotAddANoticeIfTheLimitIsNotExceeded(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(value INTEGER);
+		INSERT INTO test(value) VALUES (1), (2);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test"})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, MaxRows: 2})
+	if response.Error != nil {
+		t.Errorf("Unexpected error - %s", response.Error)
+	}
+
+	if notices := response.Frames[0].Meta.Notices; len(notices) != 0 {
+		t.Errorf("Expected no notices but got - %+v", notices)
+	}
+}
+
+func TestQueryMaxRowsCanOnlyLowerTheDatasourceLimit(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(value INTEGER);
+		INSERT INTO test(value) VALUES (1), (2), (3), (4);
+	`)
+	defer cleanup()
+
+	for _, testCase := range []struct {
+		datasourceLimit int
+		queryLimit      int
+		expectedRows    int
+	}{
+		{datasourceLimit: 0, queryLimit: 0, expectedRows: 4},
+		{datasourceLimit: 0, queryLimit: 3, expectedRows: 3},
+		{datasourceLimit: 3, queryLimit: 0, expectedRows: 3},
+		{datasourceLimit: 3, queryLimit: 1, expectedRows: 1},
+		{datasourceLimit: 1, queryLimit: 3, expectedRows: 1},
+	} {
+		dataQuery := getDataQuery(
+			queryModel{QueryText: "SELECT * FROM test", MaxRows: testCase.queryLimit},
+		)
+
+		response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, MaxRows: testCase.datasourceLimit})
+		if response.Error != nil {
+			t.Fatalf("Unexpected error - %s", response.Error)
+		}
+
+		if rows, _ := response.Frames[0].RowLen(); rows != testCase.expectedRows {
+			t.Errorf("Expected %d rows for %+v but got - %d", testCase.expectedRows, testCase, rows)
+		}
+	}
+}
+
+func TestQueryShouldBeInterruptedAfterTheTimeout(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: `
+		WITH RECURSIVE counter(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM counter)
+		SELECT max(x) FROM counter
+	`})
+
+	start := time.Now()
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, QueryTimeout: 1})
+	if response.Error == nil {
+		t.Fatalf("Expected timeout error but got nothing. Response: %+v", response)
+	}
+
+	if !strings.Contains(response.Error.Error(), "did not finish within the timeout") {
+		t.Errorf("Unexpected error message: %s", response.Error.Error())
+	}
+	if duration := time.Since(start); duration > 5*time.Second {
+		t.Errorf("Expected the query to be interrupted after 1 second but it took %s", duration)
+	}
+}
+
+func TestQueryShouldOnlyReportItsOwnTimeout(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: `
+		WITH RECURSIVE counter(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM counter)
+		SELECT max(x) FROM counter
+	`})
+
+	// the deadline of the request is not the timeout of the query
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	response := query(dataQuery, ds.pluginConfig, ds.db, ctx)
+	if response.Error == nil {
+		t.Fatalf("Expected an error but got nothing. Response: %+v", response)
+	}
+
+	if strings.Contains(response.Error.Error(), "did not finish within the timeout") {
+		t.Errorf("Unexpected error message: %s", response.Error.Error())
+	}
+}
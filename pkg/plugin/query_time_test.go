@@ -1,12 +1,13 @@
 package plugin
 
 import (
-	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
@@ -22,7 +23,7 @@ func TestQueryWithTimeColumn(t *testing.T) {
 		QueryText: "SELECT * FROM test", TimeColumns: []string{"time"},
 	})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -68,7 +69,7 @@ func TestQueryWithTimeStringColumn(t *testing.T) {
 		QueryText: "SELECT * FROM test", TimeColumns: []string{"time", "time_string"},
 	})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -113,7 +114,7 @@ func TestUnixTimestampAsString(t *testing.T) {
 		QueryText: "SELECT * FROM test", TimeColumns: []string{"time"},
 	})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -138,3 +139,243 @@ func TestUnixTimestampAsString(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+// TestConfiguredTimeParsing tests the time parsing settings of the datasource and the query
+func TestConfiguredTimeParsing(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(julian REAL, millis INTEGER, formatted TEXT);
+		INSERT INTO test(julian, millis, formatted)
+		VALUES (julianday('2024-01-02 15:04:05'), 21, '02.01.2024 15:04');
+	`)
+	defer cleanup()
+
+	queryText := "SELECT julian, millis, formatted FROM test"
+	config := pluginConfig{Path: dbPath, EpochUnit: epochUnitJulianDay, TimeFormat: "02.01.2006 15:04"}
+
+	response := runQuery(t, getDataQuery(queryModel{
+		QueryText: queryText, TimeColumns: []string{"julian", "formatted"},
+	}), config)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("julian", nil, []*time.Time{timePointer(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC))}),
+		data.NewField("millis", nil, []*int64{intPointer(21)}),
+		data.NewField("formatted", nil, []*time.Time{timePointer(time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC))}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+
+	// the settings of the query take precedence
+	response = runQuery(t, getDataQuery(queryModel{
+		QueryText: queryText, TimeColumns: []string{"millis"}, EpochUnit: epochUnitMilliseconds,
+	}), config)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	if diff := cmp.Diff(
+		data.NewField("millis", nil, []*time.Time{timePointer(time.UnixMilli(21))}),
+		response.Frames[0].Fields[1],
+		cmpOption...,
+	); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUnsupportedEpochUnit(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1", EpochUnit: "minutes"})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || response.Error.Error() != "unsupported epoch unit: minutes" {
+		t.Errorf("Expected an unsupported epoch unit error but got %v", response.Error)
+	}
+}
+
+// TestDateTimeColumns tests the values of DATE and DATETIME columns, which the driver parses, and
+// the detection of julian days
+func TestDateTimeColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(created DATETIME, day DATE, value INTEGER);
+		INSERT INTO test(created, day, value)
+		VALUES ('2024-01-02 15:04:05', '2024-01-02', 1), ('2024-01-03T10:00:00Z', '2024-01-03', 2);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   "SELECT created, day, julianday(created) AS julian, value FROM test ORDER BY value",
+		TimeColumns: []string{"julian"},
+		ColumnTypes: map[string]string{"day": "string"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, Timezone: "Europe/Berlin"})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("created", nil, []*time.Time{
+			timePointer(time.Date(2024, 1, 2, 14, 4, 5, 0, time.UTC)),
+			timePointer(time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)),
+		}),
+		data.NewField("day", nil, []*string{
+			strPointer("2024-01-02 00:00:00"), strPointer("2024-01-03 00:00:00"),
+		}),
+		data.NewField("julian", nil, []*time.Time{
+			timePointer(time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)),
+			timePointer(time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)),
+		}),
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(2)}),
+	)
+
+	if diff := cmp.Diff(expectedFrame.Fields, response.Frames[0].Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if len(response.Frames[0].Meta.Notices) != 0 {
+		t.Errorf("Expected no notices but got %+v", response.Frames[0].Meta.Notices)
+	}
+}
+
+// TestTimezoneOfTimeStrings tests that time strings without an offset are in the configured timezone
+func TestTimezoneOfTimeStrings(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts TEXT, value INTEGER);
+		INSERT INTO test(ts, value)
+		VALUES	('2021-02-03 04:05:06', 1),
+				('2021-02-03T04:05:06Z', 2),
+				('2021-07-03', 3),
+				('2021-02-03 06:05:07', 4);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT ts, value FROM test WHERE $__timeFilter(ts) ORDER BY value"
+	dataQuery := getDataQuery(queryModel{QueryText: queryText, TimeColumns: []string{"ts"}})
+	dataQuery.TimeRange = backend.TimeRange{
+		From: time.Date(2021, 2, 3, 3, 5, 6, 0, time.UTC),
+		To:   time.Date(2021, 7, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, Timezone: "Europe/Berlin"})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("ts", nil, []*time.Time{
+			timePointer(time.Date(2021, 2, 3, 3, 5, 6, 0, time.UTC)),
+			timePointer(time.Date(2021, 2, 3, 4, 5, 6, 0, time.UTC)),
+			timePointer(time.Date(2021, 7, 2, 22, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 2, 3, 5, 5, 7, 0, time.UTC)),
+		}),
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(2), intPointer(3), intPointer(4)}),
+	)
+
+	if diff := cmp.Diff(expectedFrame.Fields, response.Frames[0].Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestTimezoneOfGapFilling tests that daily buckets start at midnight of the configured timezone,
+// also across a change of daylight saving time (2021-03-28 in Europe/Berlin)
+func TestTimezoneOfGapFilling(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts TEXT, value INTEGER);
+		INSERT INTO test(ts, value)
+		VALUES ('2021-03-26 10:00:00', 1), ('2021-03-26 23:30:00', 2), ('2021-03-28 10:00:00', 3);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT $__timeGroupAlias(ts, 1d, NULL, range), sum(value) AS value " +
+			"FROM test GROUP BY 1 ORDER BY 1",
+		TimeColumns: []string{"time"},
+	})
+	dataQuery.TimeRange = backend.TimeRange{
+		From: time.Date(2021, 3, 25, 23, 0, 0, 0, time.UTC),
+		To:   time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC),
+	}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, Timezone: "Europe/Berlin"})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("time", nil, []*time.Time{
+			timePointer(time.Date(2021, 3, 25, 23, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 26, 23, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 27, 23, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 28, 22, 0, 0, 0, time.UTC)),
+		}),
+		data.NewField("value", nil, []*int64{intPointer(3), nil, intPointer(3), nil}),
+	)
+
+	if diff := cmp.Diff(expectedFrame.Fields, response.Frames[0].Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestTimezoneOfUnixEpochGapFilling tests that the buckets of unix timestamps are aligned in UTC
+// (like the grouping of the query), also if a timezone is configured
+func TestTimezoneOfUnixEpochGapFilling(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts INTEGER, value INTEGER);
+		INSERT INTO test(ts, value)
+		VALUES (unixepoch('2021-03-26 10:00:00'), 1), (unixepoch('2021-03-28 10:00:00'), 2);
+	`)
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT $__unixEpochGroupAlias(ts, 86400, NULL, range), sum(value) AS value " +
+			"FROM test GROUP BY 1 ORDER BY 1",
+		TimeColumns: []string{"time"},
+	})
+	dataQuery.TimeRange = backend.TimeRange{
+		From: time.Date(2021, 3, 25, 23, 0, 0, 0, time.UTC),
+		To:   time.Date(2021, 3, 29, 10, 0, 0, 0, time.UTC),
+	}
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, Timezone: "Europe/Berlin"})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("time", nil, []*time.Time{
+			timePointer(time.Date(2021, 3, 25, 0, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 26, 0, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 28, 0, 0, 0, 0, time.UTC)),
+			timePointer(time.Date(2021, 3, 29, 0, 0, 0, 0, time.UTC)),
+		}),
+		data.NewField("value", nil, []*int64{nil, intPointer(1), nil, intPointer(2), nil}),
+	)
+
+	if diff := cmp.Diff(expectedFrame.Fields, response.Frames[0].Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestUnsupportedTimezone(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1"})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath, Timezone: "Mars/Olympus"})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "unsupported timezone 'Mars/Olympus'") {
+		t.Errorf("Expected an unsupported timezone error but got %v", response.Error)
+	}
+}
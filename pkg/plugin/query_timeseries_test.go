@@ -1,7 +1,6 @@
 package plugin
 
 import (
-	"context"
 	"testing"
 	"time"
 
@@ -29,7 +28,7 @@ func TestIgnoreNonTimeSeriesQuery(t *testing.T) {
 	})
 	dataQuery.QueryType = tableType
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -84,7 +83,7 @@ func TestIgnoreWideTimeSeriesQuery(t *testing.T) {
 	})
 	dataQuery.QueryType = "time series"
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -136,7 +135,7 @@ func TestConvertLongTimeSeriesQuery(t *testing.T) {
 	})
 	dataQuery.QueryType = "time series"
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -194,3 +193,51 @@ func TestConvertLongTimeSeriesQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertLongTimeSeriesQueryWithDatesAsLabels(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(ts INTEGER, value INTEGER);
+		INSERT INTO test(ts, value) VALUES (86400, 1), (172800, 2);
+	`)
+	defer cleanup()
+
+	// the computed column has dates as values, but it is a label and no time column
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   "SELECT ts, date(ts, 'unixepoch') AS day, value FROM test",
+		TimeColumns: []string{"ts"},
+	})
+	dataQuery.QueryType = timeSeriesType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	if len(response.Frames) != 2 {
+		t.Fatalf(
+			"Expected two frames but got - %d: Frames %+v", len(response.Frames), response.Frames,
+		)
+	}
+
+	times := []time.Time{time.Unix(86400, 0), time.Unix(172800, 0)}
+	expectedOutputFrames := []*data.Frame{
+		data.NewFrame(
+			"",
+			data.NewField("ts", nil, times),
+			data.NewField("value", map[string]string{"day": "1970-01-02"}, []*int64{intPointer(1), nil}),
+		),
+		data.NewFrame(
+			"",
+			data.NewField("ts", nil, times),
+			data.NewField("value", map[string]string{"day": "1970-01-03"}, []*int64{nil, intPointer(2)}),
+		),
+	}
+
+	for idx, frame := range response.Frames {
+		// we use the response as we do not care about the executed query (tested elsewhere)
+		expectedOutputFrames[idx].Meta = frame.Meta
+		if diff := cmp.Diff(expectedOutputFrames[idx], frame, cmpOption...); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
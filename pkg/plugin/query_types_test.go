@@ -1,8 +1,11 @@
 package plugin
 
 import (
-	"context"
+	"encoding/json"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -22,7 +25,7 @@ func TestCTETableQuery(t *testing.T) {
 	`
 	dataQuery := getDataQuery(queryModel{QueryText: baseQuery})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -61,7 +64,7 @@ func TestMixedTypes(t *testing.T) {
 
 	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test"})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -95,7 +98,7 @@ func TestSimpleTableQuery(t *testing.T) {
 
 	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test"})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -134,7 +137,7 @@ func TestNullValues(t *testing.T) {
 
 	dataQuery := getDataQuery(queryModel{QueryText: "SELECT * FROM test"})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -171,7 +174,7 @@ func TestNullValuesCTE(t *testing.T) {
 	`
 	dataQuery := getDataQuery(queryModel{QueryText: baseQuery})
 
-	response := query(dataQuery, pluginConfig{Path: dbPath}, context.Background())
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
 	if response.Error != nil {
 		t.Errorf("Unexpected error - %s", response.Error)
 	}
@@ -194,3 +197,248 @@ func TestNullValuesCTE(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+// TestColumnTypeOverrides tests the explicit column types of the query model
+func TestColumnTypeOverrides(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, value INTEGER, flag INTEGER, payload TEXT, ts TEXT);
+		INSERT INTO test(id, value, flag, payload, ts)
+		VALUES (1, 1, 1, '{"a": 1}', '1612325106'), (2, 2, 0, '[1, 2]', '2021-02-03T04:05:06Z');
+	`)
+	defer cleanup()
+
+	queryText := `
+		SELECT CASE WHEN id = 1 THEN 1 ELSE 2.5 END AS computed, value, flag, payload, ts
+		FROM test ORDER BY id
+	`
+	dataQuery := getDataQuery(queryModel{
+		QueryText: queryText,
+		ColumnTypes: map[string]string{
+			"computed": "float", "value": "string", "flag": "bool", "payload": "json", "ts": "TIME",
+		},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	objectPayload, arrayPayload := json.RawMessage(`{"a": 1}`), json.RawMessage(`[1, 2]`)
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("computed", nil, []*float64{floatPointer(1), floatPointer(2.5)}),
+		data.NewField("value", nil, []*string{strPointer("1"), strPointer("2")}),
+		data.NewField("flag", nil, []*bool{boolPointer(true), boolPointer(false)}),
+		data.NewField("payload", nil, []*json.RawMessage{&objectPayload, &arrayPayload}),
+		data.NewField("ts", nil, []*time.Time{unixTimePointer(1612325106), unixTimePointer(1612325106)}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestColumnTypeCoercionWarnings tests the warnings for values changed by the conversion
+func TestColumnTypeCoercionWarnings(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(amount INTEGER, flag TEXT, payload TEXT);
+		INSERT INTO test(amount, flag, payload)
+		VALUES (1, 'true', '{}'), (2.5, 'maybe', 'not json'), (3.5, NULL, NULL);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT amount, flag, payload FROM test"
+	dataQuery := getDataQuery(queryModel{
+		QueryText:   queryText,
+		ColumnTypes: map[string]string{"amount": "int", "flag": "bool", "payload": "json"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	emptyObject := json.RawMessage(`{}`)
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("amount", nil, []*int64{intPointer(1), intPointer(2), intPointer(3)}),
+		data.NewField("flag", nil, []*bool{boolPointer(true), nil, nil}),
+		data.NewField("payload", nil, []*json.RawMessage{&emptyObject, nil, nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: queryText,
+		Notices: []data.Notice{
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "2 values of the column amount lost precision when converting to integer",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column flag could not be converted to bool and were replaced with NULL",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column payload could not be converted to json and were replaced with NULL",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestColumnTypeOverridesShouldRejectUnknownTypes(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{
+		QueryText: "SELECT 1 AS value", ColumnTypes: map[string]string{"value": "decimal"},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "unsupported type 'decimal'") {
+		t.Errorf("Expected an unsupported type error but got: %v", response.Error)
+	}
+}
+
+func TestBooleanColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, active BOOLEAN, enabled BOOL, value REAL);
+		INSERT INTO test(id, active, enabled, value)
+		VALUES (1, 1, 'true', 1.5), (2, 0, 'false', 0.5), (3, NULL, NULL, NULL);
+	`)
+	defer cleanup()
+
+	queryText := "SELECT active, enabled, value > 1 AS high, value FROM test ORDER BY id"
+	dataQuery := getDataQuery(queryModel{QueryText: queryText, BoolColumns: []string{"high"}})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("active", nil, []*bool{boolPointer(true), boolPointer(false), nil}),
+		data.NewField("enabled", nil, []*bool{boolPointer(true), boolPointer(false), nil}),
+		data.NewField("high", nil, []*bool{boolPointer(true), boolPointer(false), nil}),
+		data.NewField("value", nil, []*float64{floatPointer(1.5), floatPointer(0.5), nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestNumericPrecision tests that numbers keep their precision (and infinite values are handled)
+func TestNumericPrecision(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, amount INTEGER, ratio REAL);
+		INSERT INTO test(id, amount, ratio)
+		VALUES (1, 9007199254740993, 9e999), (2, 2.5, 1e-7), (3, NULL, NULL);
+	`)
+	defer cleanup()
+
+	queryText := `
+		SELECT amount, CASE id WHEN 1 THEN 'none' WHEN 2 THEN 0.1 ELSE 1e21 END AS label,
+			ratio, ratio AS truncated, ratio AS payload, ratio AS ts
+		FROM test ORDER BY id
+	`
+	dataQuery := getDataQuery(queryModel{
+		QueryText: queryText,
+		ColumnTypes: map[string]string{
+			"truncated": "int", "payload": "json", "ts": "time",
+		},
+	})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	infinity := math.Inf(1)
+	smallRatio := json.RawMessage(`1e-07`)
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("amount", nil, []*float64{floatPointer(9007199254740993), floatPointer(2.5), nil}),
+		data.NewField("label", nil, []*string{strPointer("none"), strPointer("0.1"), strPointer("1e+21")}),
+		data.NewField("ratio", nil, []*float64{&infinity, floatPointer(1e-7), nil}),
+		data.NewField("truncated", nil, []*int64{nil, intPointer(0), nil}),
+		data.NewField("payload", nil, []*json.RawMessage{nil, &smallRatio, nil}),
+		data.NewField("ts", nil, []*time.Time{nil, timePointer(time.Unix(0, 100)), nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		ExecutedQueryString: queryText,
+		Notices: []data.Notice{
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column amount lost precision when converting to float",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column truncated could not be converted to integer and were replaced with NULL",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column truncated lost precision when converting to integer",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column payload could not be converted to json and were replaced with NULL",
+			},
+			{
+				Severity: data.NoticeSeverityWarning,
+				Text:     "1 values of the column ts could not be converted to time and were replaced with NULL",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// TestTypeInferenceOfComputedColumns tests that the type of computed columns fits all values
+func TestTypeInferenceOfComputedColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, value REAL, note TEXT);
+		INSERT INTO test(id, value, note)
+		VALUES (1, NULL, NULL), (2, 2, 'late'), (3, 3.5, NULL);
+	`)
+	defer cleanup()
+
+	queryText := `
+		SELECT
+			CASE WHEN value IS NULL THEN 'N/A' ELSE value END AS placeholder,
+			CASE WHEN note IS NULL THEN id ELSE note END AS label,
+			CASE WHEN id = 3 THEN value ELSE id END AS amount,
+			datetime(1612325106 + id, 'unixepoch') AS created,
+			NULL AS empty
+		FROM test ORDER BY id
+	`
+	dataQuery := getDataQuery(queryModel{QueryText: queryText})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("placeholder", nil, []*string{strPointer("N/A"), strPointer("2"), strPointer("3.5")}),
+		data.NewField("label", nil, []*string{strPointer("1"), strPointer("late"), strPointer("3")}),
+		data.NewField("amount", nil, []*float64{floatPointer(1), floatPointer(2), floatPointer(3.5)}),
+		data.NewField("created", nil, []*time.Time{
+			unixTimePointer(1612325107), unixTimePointer(1612325108), unixTimePointer(1612325109),
+		}),
+		data.NewField("empty", nil, []*float64{nil, nil, nil}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: queryText}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
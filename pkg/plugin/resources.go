@@ -0,0 +1,256 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+)
+
+// schemaTable is a table of the database as returned by the schema endpoints
+type schemaTable struct {
+	Name string `json:"name"`
+}
+
+type schemaColumn struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	NotNull    bool   `json:"notNull"`
+	PrimaryKey bool   `json:"primaryKey"`
+}
+
+type schemaIndex struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+type schemaView struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+// CallResource handles the resource endpoints of the datasource. They provide the schema of the
+// database (e.g. for the autocompletion of the query editor):
+//   - GET /tables: the tables of the database
+//   - GET /tables/{name}/columns: the columns of a table or view
+//   - GET /columns: the columns of all tables and views by their name
+//   - GET /indexes: the indexes of the database (optionally filtered with ?table=)
+//   - GET /views: the views of the database including their definition
+func (ds *sqliteDatasource) CallResource(
+	ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender,
+) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tables", ds.schemaHandler(getTables))
+	mux.HandleFunc("GET /tables/{name}/columns", ds.schemaHandler(getColumns))
+	mux.HandleFunc("GET /columns", ds.schemaHandler(getAllColumns))
+	mux.HandleFunc("GET /indexes", ds.schemaHandler(getIndexes))
+	mux.HandleFunc("GET /views", ds.schemaHandler(getViews))
+
+	return httpadapter.New(mux).CallResource(ctx, req, sender)
+}
+
+// schemaHandler applies the protections of the queries (blocked paths and the query timeout) to
+// a schema endpoint and responds with the result as JSON
+func (ds *sqliteDatasource) schemaHandler(
+	getSchema func(db *sql.DB, req *http.Request, ctx context.Context) (interface{}, error),
+) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		if IsPathBlocked(ds.pluginConfig.Path) {
+			http.Error(rw, "path contains blocked term from GF_PLUGIN_BLOCK_LIST", http.StatusForbidden)
+			return
+		}
+
+		ctx := req.Context()
+		if ds.pluginConfig.QueryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(
+				ctx, time.Duration(ds.pluginConfig.QueryTimeout)*time.Second,
+			)
+			defer cancel()
+		}
+
+		result, err := getSchema(ds.db, req, ctx)
+		if err != nil {
+			log.DefaultLogger.Error("Could not read the schema", "path", req.URL.Path, "err", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if result == nil {
+			http.Error(rw, "not found", http.StatusNotFound)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(rw).Encode(result)
+		if err != nil {
+			log.DefaultLogger.Error("Could not write the schema", "path", req.URL.Path, "err", err)
+		}
+	}
+}
+
+func getTables(db *sql.DB, _ *http.Request, ctx context.Context) (interface{}, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_schema
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := []schemaTable{}
+	for rows.Next() {
+		var table schemaTable
+		if err := rows.Scan(&table.Name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+// getColumns returns the columns of a table or view. The result is nil for unknown tables
+func getColumns(db *sql.DB, req *http.Request, ctx context.Context) (interface{}, error) {
+	columns, err := tableColumns(db, req.PathValue("name"), ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// every table has at least one column
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	return columns, nil
+}
+
+// getAllColumns returns the columns of all tables and views by their name. Views, whose columns
+// cannot be read (e.g. because a table of the view was dropped), are skipped
+func getAllColumns(db *sql.DB, _ *http.Request, ctx context.Context) (interface{}, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name FROM sqlite_schema
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a single query over all tables (joining pragma_table_info) would fail for any broken view
+	columnsByTable := map[string][]schemaColumn{}
+	for _, name := range names {
+		columns, err := tableColumns(db, name, ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.DefaultLogger.Debug("Could not read the columns of the table", "table", name, "err", err)
+			continue
+		}
+		columnsByTable[name] = columns
+	}
+
+	return columnsByTable, nil
+}
+
+// tableColumns returns the columns of a table or view (in the order of the definition)
+func tableColumns(db *sql.DB, table string, ctx context.Context) ([]schemaColumn, error) {
+	rows, err := db.QueryContext(
+		ctx, `SELECT name, type, "notnull", pk FROM pragma_table_info(?) ORDER BY cid`, table,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := []schemaColumn{}
+	for rows.Next() {
+		var column schemaColumn
+		var primaryKeyIndex int
+		if err := rows.Scan(&column.Name, &column.Type, &column.NotNull, &primaryKeyIndex); err != nil {
+			return nil, err
+		}
+		column.PrimaryKey = primaryKeyIndex > 0
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func getIndexes(db *sql.DB, req *http.Request, ctx context.Context) (interface{}, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT list.name, tables.name, list."unique", info.name
+		FROM sqlite_schema AS tables
+		JOIN pragma_index_list(tables.name) AS list
+		JOIN pragma_index_info(list.name) AS info
+		WHERE tables.type = 'table' AND (? = '' OR tables.name = ?)
+		ORDER BY tables.name, list.name, info.seqno
+	`, req.URL.Query().Get("table"), req.URL.Query().Get("table"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := []*schemaIndex{}
+	for rows.Next() {
+		var index schemaIndex
+		var column sql.NullString
+		if err := rows.Scan(&index.Name, &index.Table, &index.Unique, &column); err != nil {
+			return nil, err
+		}
+
+		// the rows contain one column of an index each
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != index.Name {
+			index.Columns = []string{}
+			indexes = append(indexes, &index)
+		}
+		// expressions have no column name
+		if column.Valid {
+			lastIndex := indexes[len(indexes)-1]
+			lastIndex.Columns = append(lastIndex.Columns, column.String)
+		}
+	}
+
+	return indexes, rows.Err()
+}
+
+func getViews(db *sql.DB, _ *http.Request, ctx context.Context) (interface{}, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT name, sql FROM sqlite_schema WHERE type = 'view' ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []schemaView{}
+	for rows.Next() {
+		var view schemaView
+		if err := rows.Scan(&view.Name, &view.SQL); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const schemaTestDB = `
+	CREATE TABLE sensors(id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	CREATE TABLE readings(time INTEGER, sensor_id INTEGER, value REAL);
+	CREATE UNIQUE INDEX sensors_name ON sensors(name);
+	CREATE INDEX readings_sensor_time ON readings(sensor_id, time);
+	CREATE VIEW latest AS SELECT max(time) AS time FROM readings;
+`
+
+func callResource(t *testing.T, ds *sqliteDatasource, path string) *backend.CallResourceResponse {
+	var response *backend.CallResourceResponse
+	resourcePath, _, _ := strings.Cut(path, "?")
+	err := ds.CallResource(
+		context.Background(),
+		&backend.CallResourceRequest{Method: http.MethodGet, Path: resourcePath, URL: path},
+		backend.CallResourceResponseSenderFunc(func(res *backend.CallResourceResponse) error {
+			response = res
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+	return response
+}
+
+func TestSchemaResources(t *testing.T) {
+	dbPath, cleanup := createTmpDB(schemaTestDB)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+
+	for _, testCase := range []struct {
+		path     string
+		expected interface{}
+	}{
+		{
+			path:     "tables",
+			expected: []schemaTable{{Name: "readings"}, {Name: "sensors"}},
+		},
+		{
+			path: "tables/sensors/columns",
+			expected: []schemaColumn{
+				{Name: "id", Type: "INTEGER", PrimaryKey: true},
+				{Name: "name", Type: "TEXT", NotNull: true},
+			},
+		},
+		{
+			path:     "tables/latest/columns",
+			expected: []schemaColumn{{Name: "time", Type: ""}},
+		},
+		{
+			path: "indexes",
+			expected: []schemaIndex{
+				{Name: "readings_sensor_time", Table: "readings", Columns: []string{"sensor_id", "time"}},
+				{Name: "sensors_name", Table: "sensors", Unique: true, Columns: []string{"name"}},
+			},
+		},
+		{
+			path: "indexes?table=sensors",
+			expected: []schemaIndex{
+				{Name: "sensors_name", Table: "sensors", Unique: true, Columns: []string{"name"}},
+			},
+		},
+		{
+			path: "views",
+			expected: []schemaView{
+				{Name: "latest", SQL: "CREATE VIEW latest AS SELECT max(time) AS time FROM readings"},
+			},
+		},
+	} {
+		response := callResource(t, ds, testCase.path)
+		if response.Status != http.StatusOK {
+			t.Errorf("Unexpected status %d for %s: %s", response.Status, testCase.path, response.Body)
+			continue
+		}
+
+		expectedBody, _ := json.Marshal(testCase.expected)
+		if diff := cmp.Diff(string(expectedBody)+"\n", string(response.Body)); diff != "" {
+			t.Errorf("Unexpected body for %s: %s", testCase.path, diff)
+		}
+	}
+}
+
+func TestSchemaResourcesShouldReturnTheColumnsOfAllTables(t *testing.T) {
+	dbPath, cleanup := createTmpDB(schemaTestDB + `
+		CREATE TABLE dropped(value INTEGER);
+		CREATE VIEW broken AS SELECT value FROM dropped;
+		DROP TABLE dropped;
+	`)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+
+	response := callResource(t, ds, "columns")
+	if response.Status != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", response.Status, response.Body)
+	}
+
+	expectedBody, _ := json.Marshal(map[string][]schemaColumn{
+		"latest": {{Name: "time", Type: ""}},
+		"readings": {
+			{Name: "time", Type: "INTEGER"},
+			{Name: "sensor_id", Type: "INTEGER"},
+			{Name: "value", Type: "REAL"},
+		},
+		"sensors": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "name", Type: "TEXT", NotNull: true},
+		},
+	})
+	if diff := cmp.Diff(string(expectedBody)+"\n", string(response.Body)); diff != "" {
+		t.Errorf("Unexpected body: %s", diff)
+	}
+}
+
+func TestSchemaResourcesShouldReturnNotFoundForUnknownTables(t *testing.T) {
+	dbPath, cleanup := createTmpDB(schemaTestDB)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+
+	response := callResource(t, ds, "tables/unknown/columns")
+	if response.Status != http.StatusNotFound {
+		t.Errorf("Expected status 404 but got %d: %s", response.Status, response.Body)
+	}
+}
+
+func TestSchemaResourcesShouldRespectTheBlockList(t *testing.T) {
+	ds := getDatasource(t, pluginConfig{Path: "/home/user/.ssh/data.db"})
+
+	response := callResource(t, ds, "tables")
+	if response.Status != http.StatusForbidden {
+		t.Errorf("Expected status 403 but got %d: %s", response.Status, response.Body)
+	}
+}
@@ -2,10 +2,13 @@ package plugin
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
@@ -19,12 +22,29 @@ import (
 // since otherwise we will only get a not implemented error response from plugin in
 // runtime.
 var (
-	_ backend.QueryDataHandler   = (*sqliteDatasource)(nil)
-	_ backend.CheckHealthHandler = (*sqliteDatasource)(nil)
+	_ backend.QueryDataHandler      = (*sqliteDatasource)(nil)
+	_ backend.CheckHealthHandler    = (*sqliteDatasource)(nil)
+	_ backend.StreamHandler         = (*sqliteDatasource)(nil)
+	_ backend.CallResourceHandler   = (*sqliteDatasource)(nil)
+	_ instancemgmt.InstanceDisposer = (*sqliteDatasource)(nil)
 )
 
+// maxOpenConnections bounds the connection pool of a single datasource instance
+const maxOpenConnections = 10
+
+// defaultMaxConcurrentQueries is the number of queries of a single request, which are
+// executed in parallel, unless the datasource configures a different limit
+const defaultMaxConcurrentQueries = 4
+
+// maxIdleConnectionTime closes pooled connections that were not used for a while.
+// This releases file handles of datasources that are rarely queried
+const maxIdleConnectionTime = 5 * time.Minute
+
 type sqliteDatasource struct {
 	pluginConfig pluginConfig
+
+	// db is the connection pool of the datasource. It is shared by all queries of the instance
+	db *sql.DB
 }
 
 type pluginConfig struct {
@@ -32,6 +52,34 @@ type pluginConfig struct {
 	PathOptions string
 	PathPrefix  string
 	AttachLimit *int64
+	// QueryOnly is set if the plugin added the query_only pragma. It is enforced on every
+	// connection, also if a query changed the pragma on a previous connection
+	QueryOnly bool `json:"-"`
+
+	// MaxConcurrentQueries limits how many queries of a single request run in parallel
+	MaxConcurrentQueries int
+	// QueryTimeout is the maximum runtime of a query in seconds (0 means no timeout)
+	QueryTimeout int
+	// MaxRows is the maximum number of rows returned by a query (0 means no limit)
+	MaxRows int
+	// MaxBlobSize is the maximum number of bytes of BLOB values. Larger values are truncated
+	// (0 means the default of 1 MiB)
+	MaxBlobSize int
+
+	// TimeFormat is the (Go) layout of time strings, which are not in a default format
+	TimeFormat string
+	// EpochUnit is the unit of numeric timestamps (s, ms, us, ns or julianDay). By default the
+	// unit is detected by the magnitude of the value
+	EpochUnit string
+	// Timezone (an IANA name) is assumed for time strings without an offset. The default is UTC
+	Timezone string
+}
+
+func (config pluginConfig) maxConcurrentQueries() int {
+	if config.MaxConcurrentQueries <= 0 {
+		return defaultMaxConcurrentQueries
+	}
+	return config.MaxConcurrentQueries
 }
 
 // NewDataSource creates a new datasource instance.
@@ -59,29 +107,82 @@ func NewDataSource(ctx context.Context, settings backend.DataSourceInstanceSetti
 		queryOnlyPragma := "_pragma=query_only(1)"
 		if config.PathOptions == "" {
 			config.PathOptions = queryOnlyPragma
+			config.QueryOnly = true
 		} else if !strings.Contains(config.PathOptions, "_pragma=query_only") {
 			config.PathOptions = config.PathOptions + "&" + queryOnlyPragma
+			config.QueryOnly = true
 		}
 	}
 
-	return &sqliteDatasource{pluginConfig: config}, nil
+	db, err := openDB(config)
+	if err != nil {
+		log.DefaultLogger.Error("Could not open database", "err", err)
+		return &sqliteDatasource{}, fmt.Errorf("error while opening database: %s", err)
+	}
+
+	return &sqliteDatasource{pluginConfig: config, db: db}, nil
+}
+
+// openDB creates the connection pool for the configured database.
+// No connection is opened until the pool is used for the first time.
+func openDB(config pluginConfig) (*sql.DB, error) {
+	db := sql.OpenDB(newConnector(config))
+
+	// every concurrently running query needs its own connection
+	poolSize := max(maxOpenConnections, config.maxConcurrentQueries())
+	db.SetMaxOpenConns(poolSize)
+	db.SetMaxIdleConns(poolSize)
+	db.SetConnMaxIdleTime(maxIdleConnectionTime)
+
+	return db, nil
+}
+
+// Dispose closes the connection pool of the datasource. It is called by Grafana before
+// an instance is replaced, e.g. because the datasource settings were changed
+func (ds *sqliteDatasource) Dispose() {
+	log.DefaultLogger.Info("Disposing instance")
+	if ds.db == nil {
+		return
+	}
+	if err := ds.db.Close(); err != nil {
+		log.DefaultLogger.Error("Error closing database", "err", err)
+	}
 }
 
 // QueryData handles multiple queries and returns multiple responses.
 // req contains the queries []DataQuery (where each query contains RefID as a unique identifier).
 // The QueryDataResponse contains a map of RefID to the response for each query, and each response
 // contains Frames ([]*Frame).
+// The queries are executed concurrently, limited by the configured maxConcurrentQueries.
 func (ds *sqliteDatasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (
 	*backend.QueryDataResponse, error,
 ) {
 	log.DefaultLogger.Debug("Received request for data")
 	response := backend.NewQueryDataResponse()
 
-	// loop over queries and execute them individually.
+	var responseLock sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ds.pluginConfig.maxConcurrentQueries())
+
 	for _, q := range req.Queries {
-		response.Responses[q.RefID] = query(q, ds.pluginConfig, ctx)
-		log.DefaultLogger.Debug("Finished query", "refID", q.RefID)
+		wg.Go(func() {
+			var queryResponse backend.DataResponse
+
+			select {
+			case slots <- struct{}{}:
+				queryResponse = query(q, ds.pluginConfig, ds.db, ctx)
+				<-slots
+			case <-ctx.Done():
+				queryResponse.Error = ctx.Err()
+			}
+
+			responseLock.Lock()
+			response.Responses[q.RefID] = queryResponse
+			responseLock.Unlock()
+			log.DefaultLogger.Debug("Finished query", "refID", q.RefID)
+		})
 	}
+	wg.Wait()
 
 	return response, nil
 }
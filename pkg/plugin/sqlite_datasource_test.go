@@ -0,0 +1,257 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func getDataSourceFromSettings(t *testing.T, config pluginConfig) *sqliteDatasource {
+	jsonData, _ := json.Marshal(config)
+	instance, err := NewDataSource(
+		context.Background(), backend.DataSourceInstanceSettings{JSONData: jsonData},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error creating the datasource - %s", err)
+	}
+
+	return instance.(*sqliteDatasource)
+}
+
+func TestQueryDataShouldReuseConnections(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath})
+	defer ds.Dispose()
+
+	request := &backend.QueryDataRequest{Queries: []backend.DataQuery{
+		getDataQuery(queryModel{QueryText: "SELECT 1 AS value"}),
+	}}
+	request.Queries[0].RefID = "A"
+
+	for i := 0; i < 3; i++ {
+		response, err := ds.QueryData(context.Background(), request)
+		if err != nil {
+			t.Fatalf("Unexpected error - %s", err)
+		}
+		if response.Responses["A"].Error != nil {
+			t.Fatalf("Unexpected error - %s", response.Responses["A"].Error)
+		}
+	}
+
+	stats := ds.db.Stats()
+	if stats.OpenConnections != 1 {
+		t.Errorf("Expected one open connection but got - %d", stats.OpenConnections)
+	}
+	if stats.Idle != 1 {
+		t.Errorf("Expected the connection to be returned to the pool but got - %+v", stats)
+	}
+}
+
+func TestPooledConnectionsShouldKeepTheAttachLimit(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath, AttachLimit: intPointer(0)})
+	defer ds.Dispose()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "ATTACH DATABASE ':memory:' AS test_name; SELECT 1"})
+
+	for i := 0; i < 2; i++ {
+		response := query(dataQuery, ds.pluginConfig, ds.db, context.Background())
+		if response.Error == nil {
+			t.Errorf("Expected error but got nothing. Response: %+v", response)
+		}
+	}
+}
+
+func TestPooledConnectionsShouldStayReadOnly(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`CREATE TABLE test(id INTEGER)`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath})
+	defer ds.Dispose()
+
+	response := query(
+		getDataQuery(queryModel{QueryText: "PRAGMA query_only = 0"}), ds.pluginConfig, ds.db, context.Background(),
+	)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	response = query(
+		getDataQuery(queryModel{QueryText: "INSERT INTO test(id) VALUES (1)"}),
+		ds.pluginConfig, ds.db, context.Background(),
+	)
+	if response.Error == nil {
+		t.Errorf("Expected error for a write after a previous query but got nothing. Response: %+v", response)
+	}
+}
+
+func TestPooledConnectionsShouldNotKeepPragmasOfPreviousQueries(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath})
+	defer ds.Dispose()
+
+	response := query(
+		getDataQuery(queryModel{QueryText: "PRAGMA cache_size = 123"}), ds.pluginConfig, ds.db, context.Background(),
+	)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	response = query(
+		getDataQuery(queryModel{QueryText: "SELECT cache_size FROM pragma_cache_size"}),
+		ds.pluginConfig, ds.db, context.Background(),
+	)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if value, _ := response.Frames[0].Fields[0].ConcreteAt(0); value == int64(123) {
+		t.Error("Expected the default cache size but got the one of the previous query")
+	}
+}
+
+func TestPooledConnectionsShouldNotKeepTransactionsOfPreviousQueries(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`CREATE TABLE test(id INTEGER)`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath})
+	defer ds.Dispose()
+	ds.db.SetMaxOpenConns(1)
+
+	response := query(
+		getDataQuery(queryModel{QueryText: "BEGIN; SELECT count(*) FROM test"}),
+		ds.pluginConfig, ds.db, context.Background(),
+	)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+
+	// the read lock of an open transaction would block writers to the database
+	insertRows(t, dbPath, "INSERT INTO test(id) VALUES (1)")
+}
+
+func TestPooledConnectionsShouldOnlyBeDiscardedAfterStateChanges(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`CREATE TABLE readings(time INTEGER, temp REAL)`)
+	defer cleanup()
+
+	for _, testCase := range []struct {
+		query               string
+		expectedConnections int
+	}{
+		{query: "SELECT time, temp FROM readings", expectedConnections: 1},
+		{query: "SELECT 'pragma' AS temp", expectedConnections: 1},
+		{query: "CREATE TEMP TABLE temporary AS SELECT 1", expectedConnections: 0},
+		{query: "SELECT 1;\n PRAGMA cache_size = 123", expectedConnections: 0},
+	} {
+		ds := getDatasource(t, pluginConfig{Path: dbPath})
+
+		response := query(
+			getDataQuery(queryModel{QueryText: testCase.query}), ds.pluginConfig, ds.db, context.Background(),
+		)
+		if response.Error != nil {
+			t.Fatalf("Unexpected error for %s - %s", testCase.query, response.Error)
+		}
+
+		if connections := ds.db.Stats().OpenConnections; connections != testCase.expectedConnections {
+			t.Errorf(
+				"Expected %d pooled connections after %s but got %d",
+				testCase.expectedConnections, testCase.query, connections,
+			)
+		}
+	}
+}
+
+func TestDisposeShouldCloseTheConnectionPool(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath})
+	ds.Dispose()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1"})
+	response := query(dataQuery, ds.pluginConfig, ds.db, context.Background())
+	if response.Error == nil {
+		t.Errorf("Expected error for a disposed datasource but got nothing. Response: %+v", response)
+	}
+}
+
+func TestQueryDataShouldReturnAllQueriesWhenRunningConcurrently(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath, MaxConcurrentQueries: 2})
+	defer ds.Dispose()
+
+	request := &backend.QueryDataRequest{}
+	refIDs := []string{"A", "B", "C", "D", "E"}
+	for idx, refID := range refIDs {
+		dataQuery := getDataQuery(queryModel{QueryText: fmt.Sprintf("SELECT %d AS value", idx)})
+		dataQuery.RefID = refID
+		request.Queries = append(request.Queries, dataQuery)
+	}
+
+	response, err := ds.QueryData(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	if len(response.Responses) != len(refIDs) {
+		t.Fatalf("Expected %d responses but got - %d", len(refIDs), len(response.Responses))
+	}
+
+	for idx, refID := range refIDs {
+		queryResponse := response.Responses[refID]
+		if queryResponse.Error != nil {
+			t.Fatalf("Unexpected error for %s - %s", refID, queryResponse.Error)
+		}
+
+		expectedFrame := data.NewFrame("", data.NewField("value", nil, []*int64{intPointer(int64(idx))}))
+		expectedFrame.Meta = &data.FrameMeta{
+			ExecutedQueryString: fmt.Sprintf("SELECT %d AS value", idx),
+		}
+		if diff := cmp.Diff(expectedFrame, queryResponse.Frames[0], cmpOption...); diff != "" {
+			t.Errorf("Unexpected frame for %s: %s", refID, diff)
+		}
+	}
+}
+
+func TestQueryDataShouldRespectCancellation(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`SELECT 1`)
+	defer cleanup()
+
+	ds := getDataSourceFromSettings(t, pluginConfig{Path: dbPath, MaxConcurrentQueries: 1})
+	defer ds.Dispose()
+
+	request := &backend.QueryDataRequest{}
+	for _, refID := range []string{"A", "B", "C"} {
+		dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1"})
+		dataQuery.RefID = refID
+		request.Queries = append(request.Queries, dataQuery)
+	}
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	response, err := ds.QueryData(cancelledCtx, request)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	for _, refID := range []string{"A", "B", "C"} {
+		if !errors.Is(response.Responses[refID].Error, context.Canceled) {
+			t.Errorf("Expected a cancellation error for %s but got - %v", refID, response.Responses[refID].Error)
+		}
+	}
+}
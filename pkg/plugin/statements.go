@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+)
+
+// statementNameRegex matches the comment naming the frame of a statement, e.g. "-- name: data"
+var statementNameRegex = regexp.MustCompile(`^--\s*name:\s*(.*?)\s*$`)
+
+// sqlStatement is a single statement of a query
+type sqlStatement struct {
+	// Name is the name of the frames of the statement (from a "-- name:" comment)
+	Name  string
+	Query string
+}
+
+// splitStatements splits the query at semicolons, which are not part of a string, an identifier or
+// a comment. Statements without code (e.g. a trailing comment) are removed. A query with a single
+// statement (or without any) is returned unchanged
+func splitStatements(query string) []sqlStatement {
+	statements := []sqlStatement{}
+	current := sqlStatement{}
+	start := 0
+	hasCode := false
+
+	finishStatement := func(end int) {
+		if hasCode {
+			current.Query = strings.TrimSpace(query[start:end])
+			statements = append(statements, current)
+		}
+		current = sqlStatement{}
+		start = end + 1
+		hasCode = false
+	}
+
+	for idx := 0; idx < len(query); idx++ {
+		switch char := query[idx]; {
+		case char == '-' && strings.HasPrefix(query[idx:], "--"):
+			end := strings.IndexByte(query[idx:], '\n')
+			if end == -1 {
+				end = len(query) - idx
+			}
+			comment := query[idx : idx+end]
+			if match := statementNameRegex.FindStringSubmatch(comment); match != nil && current.Name == "" {
+				current.Name = match[1]
+			}
+			idx += end
+		case char == '/' && strings.HasPrefix(query[idx:], "/*"):
+			end := strings.Index(query[idx+2:], "*/")
+			if end == -1 {
+				idx = len(query)
+			} else {
+				idx += end + 3
+			}
+		case char == '\'' || char == '"' || char == '`' || char == '[':
+			closing := char
+			if char == '[' {
+				closing = ']'
+			}
+			// quotes are escaped by doubling them, which is the same as two adjacent strings
+			end := strings.IndexByte(query[idx+1:], closing)
+			if end == -1 {
+				idx = len(query)
+			} else {
+				idx += end + 1
+			}
+			hasCode = true
+		case char == ';':
+			finishStatement(idx)
+		case char != ' ' && char != '\t' && char != '\n' && char != '\r':
+			hasCode = true
+		}
+	}
+	finishStatement(len(query))
+
+	switch len(statements) {
+	case 0:
+		return []sqlStatement{{Query: query}}
+	case 1:
+		statements[0].Query = query
+	}
+	return statements
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected []sqlStatement
+	}{
+		{
+			name:     "single statement",
+			query:    "SELECT 1;\n-- a comment\n",
+			expected: []sqlStatement{{Query: "SELECT 1;\n-- a comment\n"}},
+		},
+		{
+			name:     "empty query",
+			query:    "",
+			expected: []sqlStatement{{Query: ""}},
+		},
+		{
+			name:  "multiple statements with names",
+			query: "-- name: data\nSELECT 1;\n-- name: thresholds\nSELECT 2",
+			expected: []sqlStatement{
+				{Name: "data", Query: "-- name: data\nSELECT 1"},
+				{Name: "thresholds", Query: "-- name: thresholds\nSELECT 2"},
+			},
+		},
+		{
+			name:  "semicolons in strings, identifiers and comments",
+			query: `SELECT ';', "a;b", [c;d] /* ; */ -- ;` + "\n;SELECT 'it''s;'",
+			expected: []sqlStatement{
+				{Query: `SELECT ';', "a;b", [c;d] /* ; */ -- ;`},
+				{Query: "SELECT 'it''s;'"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, splitStatements(tc.query)); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMultipleStatements(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE measurements(value INTEGER);
+		INSERT INTO measurements VALUES (1), (2);
+	`)
+	defer cleanup()
+
+	queryText := `
+		PRAGMA case_sensitive_like = true;
+		-- name: data
+		SELECT value FROM measurements;
+		-- name: thresholds
+		SELECT 10 AS warning, 20 AS critical;
+	`
+	dataQuery := getDataQuery(queryModel{QueryText: queryText})
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 2 {
+		t.Fatalf("Expected two frames but got %d", len(response.Frames))
+	}
+
+	expectedData := data.NewFrame(
+		"data",
+		data.NewField("value", nil, []*int64{intPointer(1), intPointer(2)}),
+	)
+	expectedData.Meta = &data.FrameMeta{
+		ExecutedQueryString: "-- name: data\n\t\tSELECT value FROM measurements",
+	}
+	expectedThresholds := data.NewFrame(
+		"thresholds",
+		data.NewField("warning", nil, []*int64{intPointer(10)}),
+		data.NewField("critical", nil, []*int64{intPointer(20)}),
+	)
+	expectedThresholds.Meta = &data.FrameMeta{
+		ExecutedQueryString: "-- name: thresholds\n\t\tSELECT 10 AS warning, 20 AS critical",
+	}
+
+	if diff := cmp.Diff(expectedData, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff(expectedThresholds, response.Frames[1], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestMultipleStatementsShouldOnlyBeSupportedForTablesAndTimeSeries(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 1 AS time; SELECT 2 AS time"})
+	dataQuery.QueryType = annotationType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "multiple statements") {
+		t.Errorf("Expected an error about multiple statements but got %v", response.Error)
+	}
+}
+
+func TestMultipleStatementsShouldShareTheirConnection(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+
+	queryText := `
+		CREATE TEMP TABLE thresholds AS SELECT 10 AS warning;
+		SELECT warning FROM thresholds;
+	`
+	response := query(getDataQuery(queryModel{QueryText: queryText}), ds.pluginConfig, ds.db, context.Background())
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 1 {
+		t.Fatalf("Expected one frame but got %d", len(response.Frames))
+	}
+
+	expectedFrame := data.NewFrame("", data.NewField("warning", nil, []*int64{intPointer(10)}))
+	expectedFrame.Meta = &data.FrameMeta{ExecutedQueryString: "SELECT warning FROM thresholds"}
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+
+	// the temporary table does not outlive the query
+	response = query(
+		getDataQuery(queryModel{QueryText: "SELECT warning FROM thresholds"}),
+		ds.pluginConfig,
+		ds.db,
+		context.Background(),
+	)
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "no such table") {
+		t.Errorf("Expected an error about the missing table but got %v", response.Error)
+	}
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// streamPathPrefix is the prefix of all channel paths of streaming queries.
+// The rest of the path identifies the query, e.g. stream/<hash of the query>
+const streamPathPrefix = "stream/"
+
+// defaultStreamInterval is the time between two polls of a streaming query
+const defaultStreamInterval = time.Second
+
+// minStreamInterval protects the database from being polled too often
+const minStreamInterval = 100 * time.Millisecond
+
+// streamKeyAlias is the name of the helper column, which holds the raw value of the key column.
+// The key column itself might have been converted (e.g. to a time) and cannot be compared. The
+// helper column is selected with a unary plus, which removes the declared type of the column, so
+// that the driver does not parse the values of DATETIME or TIMESTAMP columns either
+const streamKeyAlias = "__stream_key"
+
+// streamModel is the query of a stream. It is sent by the frontend when subscribing
+type streamModel struct {
+	queryModel
+
+	// KeyColumn is an incrementing column (e.g. an id or a timestamp). After the first poll only
+	// rows with a key greater than the key of the last sent row are queried
+	KeyColumn string `json:"streamKeyColumn"`
+	// Interval is the time between two polls in milliseconds
+	Interval int64 `json:"streamInterval"`
+	// From is the start of the time range (unix milliseconds) used by macros and variables.
+	// The end of the time range is the time of the poll
+	From int64 `json:"from"`
+}
+
+func (model streamModel) interval() time.Duration {
+	if model.Interval <= 0 {
+		return defaultStreamInterval
+	}
+	return max(time.Duration(model.Interval)*time.Millisecond, minStreamInterval)
+}
+
+func parseStreamModel(path string, rawModel json.RawMessage) (streamModel, error) {
+	var model streamModel
+	if !strings.HasPrefix(path, streamPathPrefix) {
+		return model, fmt.Errorf("unknown stream path: %s", path)
+	}
+
+	err := json.Unmarshal(rawModel, &model)
+	if err != nil {
+		return model, fmt.Errorf("could not unmarshal stream query: %w", err)
+	}
+	if model.KeyColumn == "" {
+		return model, fmt.Errorf("a streaming query needs a key column")
+	}
+
+	err = model.applyBuilder()
+	if err != nil {
+		return model, err
+	}
+
+	return model, nil
+}
+
+// SubscribeStream is called when a panel subscribes to a streaming query
+func (ds *sqliteDatasource) SubscribeStream(
+	_ context.Context, req *backend.SubscribeStreamRequest,
+) (*backend.SubscribeStreamResponse, error) {
+	_, err := parseStreamModel(req.Path, req.Data)
+	if err != nil {
+		log.DefaultLogger.Error("Rejected stream subscription", "path", req.Path, "err", err)
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusNotFound}, nil
+	}
+	if IsPathBlocked(ds.pluginConfig.Path) {
+		log.DefaultLogger.Error("Rejected stream subscription of a blocked database path", "path", req.Path)
+		return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusPermissionDenied}, nil
+	}
+
+	return &backend.SubscribeStreamResponse{Status: backend.SubscribeStreamStatusOK}, nil
+}
+
+// PublishStream is called when a client sends data to a stream. The streams are read-only
+func (ds *sqliteDatasource) PublishStream(
+	_ context.Context, _ *backend.PublishStreamRequest,
+) (*backend.PublishStreamResponse, error) {
+	return &backend.PublishStreamResponse{Status: backend.PublishStreamStatusPermissionDenied}, nil
+}
+
+// RunStream polls the query of the stream until all subscribers are gone. The first poll sends
+// the full result of the query and every following poll only sends the new rows.
+// If possible the database file is watched and the query is only polled after new data was
+// committed. The stream interval is then the minimum time between two polls
+func (ds *sqliteDatasource) RunStream(
+	ctx context.Context, req *backend.RunStreamRequest, sender *backend.StreamSender,
+) error {
+	model, err := parseStreamModel(req.Path, req.Data)
+	if err != nil {
+		return err
+	}
+	// the watcher opens the database, so the path is checked before the first poll
+	if IsPathBlocked(ds.pluginConfig.Path) {
+		return fmt.Errorf("path contains blocked term from GF_PLUGIN_BLOCK_LIST")
+	}
+	log.DefaultLogger.Debug("Starting stream", "path", req.Path, "interval", model.interval())
+
+	ticker := time.NewTicker(model.interval())
+	defer ticker.Stop()
+
+	watcher, err := newDatabaseWatcher(ds.pluginConfig, ctx)
+	if err != nil {
+		log.DefaultLogger.Info("Could not watch the database. Polling instead", "path", req.Path, "err", err)
+	} else {
+		defer watcher.Close()
+	}
+
+	var lastKey interface{}
+	for {
+		frame, key, err := pollStream(model, lastKey, ds.pluginConfig, ds.db, ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.DefaultLogger.Error("Could not poll stream", "path", req.Path, "err", err)
+			return err
+		}
+
+		if frame.Rows() > 0 {
+			err = sender.SendFrame(frame, data.IncludeAll)
+			if err != nil {
+				log.DefaultLogger.Error("Could not send stream frame", "path", req.Path, "err", err)
+				return err
+			}
+		}
+		if key != nil {
+			lastKey = key
+		}
+
+		if watcher != nil {
+			err = watcher.waitForChange(ctx)
+			if err != nil && ctx.Err() == nil {
+				log.DefaultLogger.Error("Could not watch the database", "path", req.Path, "err", err)
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.DefaultLogger.Debug("Stopped stream", "path", req.Path)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollStream queries the rows with a key greater than lastKey (or all rows if lastKey is nil).
+// It returns the rows as a frame in the long format and the key of the last row
+func pollStream(
+	model streamModel, lastKey interface{}, config pluginConfig, db *sql.DB, ctx context.Context,
+) (*data.Frame, interface{}, error) {
+	if IsPathBlocked(config.Path) {
+		return nil, nil, fmt.Errorf("path contains blocked term from GF_PLUGIN_BLOCK_LIST")
+	}
+
+	dataQuery := backend.DataQuery{
+		QueryType: tableType,
+		TimeRange: backend.TimeRange{From: time.UnixMilli(model.From), To: time.Now()},
+		Interval:  model.interval(),
+	}
+	queryConfig, err := newQueryConfig(model.queryModel, dataQuery, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if timeout := stricterLimit(config.QueryTimeout, model.QueryTimeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	err = replaceVariables(&queryConfig, dataQuery)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = applyMacros(&queryConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the query is wrapped to filter and sort by the key. The new lines prevent a trailing
+	// comment of the query from commenting out the rest
+	keyColumn := quoteIdentifier(model.KeyColumn)
+	baseQuery := strings.TrimSuffix(strings.TrimSpace(queryConfig.FinalQuery), ";")
+	filter := ""
+	if lastKey != nil {
+		filter = fmt.Sprintf(" WHERE %s > ?", keyColumn)
+		// the arguments of the query (e.g. of the builder) are bound before the key
+		queryConfig.Arguments = append(slices.Clip(queryConfig.Arguments), lastKey)
+	}
+	queryConfig.FinalQuery = fmt.Sprintf(
+		"SELECT *, +%s AS %s FROM (\n%s\n)%s ORDER BY %s",
+		keyColumn, streamKeyAlias, baseQuery, filter, keyColumn,
+	)
+	queryConfig.RawColumns = []string{streamKeyAlias}
+
+	conn, err := queryConn(config, db, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer releaseQueryConn(conn, queryConfig.FinalQuery)
+
+	columns, err := fetchData(conn, &queryConfig, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyColumnIndex := len(columns) - 1
+	if keyColumnIndex < 0 || columns[keyColumnIndex].Name != streamKeyAlias {
+		return nil, nil, fmt.Errorf("could not find the key column %s", model.KeyColumn)
+	}
+	key := lastRowValue(columns[keyColumnIndex])
+
+	frame := columnsToFrame(columns[:keyColumnIndex])
+	frame.Meta = queryConfig.frameMeta()
+
+	return frame, key, nil
+}
+
+// lastRowValue returns the value of the last row of the column or nil for an empty column
+func lastRowValue(column *sqlColumn) interface{} {
+	switch column.Type {
+	case "INTEGER":
+		if value := rowValue(column.IntData, len(column.IntData)-1); value != nil {
+			return *value
+		}
+	case "FLOAT":
+		if value := rowValue(column.FloatData, len(column.FloatData)-1); value != nil {
+			return *value
+		}
+	case "STRING":
+		if value := rowValue(column.StringData, len(column.StringData)-1); value != nil {
+			return *value
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes a column name to be used in an SQL statement
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
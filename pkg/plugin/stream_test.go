@@ -0,0 +1,332 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// streamPackets collects the packets sent to a stream
+type streamPackets chan *backend.StreamPacket
+
+func (packets streamPackets) Send(packet *backend.StreamPacket) error {
+	packets <- packet
+	return nil
+}
+
+func insertRows(t *testing.T, dbPath string, statement string) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Could not open database - %s", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(statement); err != nil {
+		t.Fatalf("Could not insert rows - %s", err)
+	}
+}
+
+func TestPollStreamShouldOnlyReturnNewRows(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, time INTEGER, value REAL);
+		INSERT INTO test(id, time, value) VALUES (1, 10, 1.1), (2, 20, 2.2);
+	`)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	model := streamModel{
+		queryModel: queryModel{
+			QueryText:   "SELECT time, value FROM test WHERE time >= $__from / 1000;",
+			TimeColumns: []string{"time"},
+		},
+		KeyColumn: "time",
+	}
+
+	frame, lastKey, err := pollStream(model, nil, ds.pluginConfig, ds.db, context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("time", nil, []*time.Time{unixTimePointer(10), unixTimePointer(20)}),
+		data.NewField("value", nil, []*float64{floatPointer(1.1), floatPointer(2.2)}),
+	)
+	if diff := cmp.Diff(expectedFrame.Fields, frame.Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if lastKey != int64(20) {
+		t.Errorf("Expected the last key to be 20 but got %v", lastKey)
+	}
+
+	insertRows(t, dbPath, "INSERT INTO test(id, time, value) VALUES (3, 30, 3.3)")
+
+	frame, lastKey, err = pollStream(model, lastKey, ds.pluginConfig, ds.db, context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	expectedFrame = data.NewFrame(
+		"",
+		data.NewField("time", nil, []*time.Time{unixTimePointer(30)}),
+		data.NewField("value", nil, []*float64{floatPointer(3.3)}),
+	)
+	if diff := cmp.Diff(expectedFrame.Fields, frame.Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+	if lastKey != int64(30) {
+		t.Errorf("Expected the last key to be 30 but got %v", lastKey)
+	}
+
+	frame, _, err = pollStream(model, lastKey, ds.pluginConfig, ds.db, context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+	if frame.Rows() != 0 {
+		t.Errorf("Expected no new rows but got %d", frame.Rows())
+	}
+}
+
+func TestPollStreamShouldAdvanceTimeKeys(t *testing.T) {
+	for _, declaredType := range []string{"TIMESTAMP", "DATETIME", ""} {
+		t.Run(fmt.Sprintf("declared as %q", declaredType), func(t *testing.T) {
+			dbPath, cleanup := createTmpDB(fmt.Sprintf(`
+				CREATE TABLE test(ts %s, value REAL);
+				INSERT INTO test(ts, value)
+				VALUES ('2024-01-02T10:00:00Z', 1.1), ('2024-01-02T11:00:00Z', 2.2);
+			`, declaredType))
+			defer cleanup()
+
+			ds := getDatasource(t, pluginConfig{Path: dbPath})
+			model := streamModel{
+				queryModel: queryModel{QueryText: "SELECT ts, value FROM test", TimeColumns: []string{"ts"}},
+				KeyColumn:  "ts",
+			}
+
+			frame, lastKey, err := pollStream(model, nil, ds.pluginConfig, ds.db, context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error - %s", err)
+			}
+			if frame.Rows() != 2 {
+				t.Errorf("Expected two rows but got %d", frame.Rows())
+			}
+			// the key keeps the stored value to be compared with the column
+			if lastKey != "2024-01-02T11:00:00Z" {
+				t.Errorf("Expected the last key to be the last stored time but got %v", lastKey)
+			}
+
+			insertRows(t, dbPath, "INSERT INTO test(ts, value) VALUES ('2024-01-02T12:00:00Z', 3.3)")
+
+			frame, lastKey, err = pollStream(model, lastKey, ds.pluginConfig, ds.db, context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error - %s", err)
+			}
+
+			expectedFrame := data.NewFrame(
+				"",
+				data.NewField(
+					"ts", nil, []*time.Time{timePointer(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC))},
+				),
+				data.NewField("value", nil, []*float64{floatPointer(3.3)}),
+			)
+			if diff := cmp.Diff(expectedFrame.Fields, frame.Fields, cmpOption...); diff != "" {
+				t.Error(diff)
+			}
+			if lastKey != "2024-01-02T12:00:00Z" {
+				t.Errorf("Expected the last key to be the new time but got %v", lastKey)
+			}
+		})
+	}
+}
+
+func TestSubscribeStreamShouldRequireAKeyColumn(t *testing.T) {
+	ds := getDatasource(t, pluginConfig{Path: "dbPath"})
+
+	for _, testCase := range []struct {
+		path     string
+		query    streamModel
+		expected backend.SubscribeStreamStatus
+	}{
+		{
+			path:     "stream/abc",
+			query:    streamModel{queryModel: queryModel{QueryText: "SELECT 1"}, KeyColumn: "id"},
+			expected: backend.SubscribeStreamStatusOK,
+		},
+		{
+			path:     "stream/abc",
+			query:    streamModel{queryModel: queryModel{QueryText: "SELECT 1"}},
+			expected: backend.SubscribeStreamStatusNotFound,
+		},
+		{
+			path:     "unknown/abc",
+			query:    streamModel{queryModel: queryModel{QueryText: "SELECT 1"}, KeyColumn: "id"},
+			expected: backend.SubscribeStreamStatusNotFound,
+		},
+	} {
+		rawQuery, _ := json.Marshal(testCase.query)
+		response, err := ds.SubscribeStream(
+			context.Background(),
+			&backend.SubscribeStreamRequest{Path: testCase.path, Data: rawQuery},
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error - %s", err)
+		}
+		if response.Status != testCase.expected {
+			t.Errorf(
+				"Expected status %v for %s but got %v", testCase.expected, rawQuery, response.Status,
+			)
+		}
+	}
+}
+
+func TestStreamsShouldRejectBlockedPaths(t *testing.T) {
+	t.Setenv("GF_PLUGIN_BLOCK_LIST", "secret")
+	dbPath := filepath.Join(t.TempDir(), "secret.db")
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	rawQuery, _ := json.Marshal(
+		streamModel{queryModel: queryModel{QueryText: "SELECT 1 AS id"}, KeyColumn: "id"},
+	)
+
+	response, err := ds.SubscribeStream(
+		context.Background(), &backend.SubscribeStreamRequest{Path: "stream/test", Data: rawQuery},
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+	if response.Status != backend.SubscribeStreamStatusPermissionDenied {
+		t.Errorf("Expected the subscription to be denied but got status %v", response.Status)
+	}
+
+	err = ds.RunStream(
+		context.Background(),
+		&backend.RunStreamRequest{Path: "stream/test", Data: rawQuery},
+		backend.NewStreamSender(make(streamPackets, 10)),
+	)
+	if err == nil || !strings.Contains(err.Error(), "blocked") {
+		t.Errorf("Expected an error about the blocked path but got %v", err)
+	}
+}
+
+func TestRunStreamShouldSendNewRows(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, value TEXT);
+		INSERT INTO test(id, value) VALUES (1, 'one');
+	`)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	rawQuery, _ := json.Marshal(streamModel{
+		queryModel: queryModel{QueryText: "SELECT id, value FROM test"},
+		KeyColumn:  "id",
+		Interval:   100,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	packets := make(streamPackets, 10)
+	finished := make(chan error)
+	go func() {
+		finished <- ds.RunStream(
+			ctx,
+			&backend.RunStreamRequest{Path: "stream/test", Data: rawQuery},
+			backend.NewStreamSender(packets),
+		)
+	}()
+
+	receiveFrame := func() *data.Frame {
+		select {
+		case packet := <-packets:
+			var frame data.Frame
+			if err := json.Unmarshal(packet.Data, &frame); err != nil {
+				t.Fatalf("Could not unmarshal frame - %s", err)
+			}
+			return &frame
+		case <-time.After(5 * time.Second):
+			t.Fatal("Received no frame from the stream")
+			return nil
+		}
+	}
+
+	frame := receiveFrame()
+	expectedFrame := data.NewFrame(
+		"",
+		data.NewField("id", nil, []*int64{intPointer(1)}),
+		data.NewField("value", nil, []*string{strPointer("one")}),
+	)
+	if diff := cmp.Diff(expectedFrame.Fields, frame.Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+
+	insertRows(t, dbPath, "INSERT INTO test(id, value) VALUES (2, 'two'), (3, 'three')")
+
+	frame = receiveFrame()
+	expectedFrame = data.NewFrame(
+		"",
+		data.NewField("id", nil, []*int64{intPointer(2), intPointer(3)}),
+		data.NewField("value", nil, []*string{strPointer("two"), strPointer("three")}),
+	)
+	if diff := cmp.Diff(expectedFrame.Fields, frame.Fields, cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+
+	cancel()
+	if err := <-finished; err != nil {
+		t.Errorf("Unexpected error when stopping the stream - %s", err)
+	}
+}
+
+// TestRunStreamShouldNotBlockQueries tests that the streams do not hold connections of the pool
+// of the queries, which would block all queries once the pool is exhausted
+func TestRunStreamShouldNotBlockQueries(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE test(id INTEGER, value TEXT);
+		INSERT INTO test(id, value) VALUES (1, 'one');
+	`)
+	defer cleanup()
+
+	ds := getDatasource(t, pluginConfig{Path: dbPath})
+	rawQuery, _ := json.Marshal(streamModel{
+		queryModel: queryModel{QueryText: "SELECT id, value FROM test"},
+		KeyColumn:  "id",
+		Interval:   100,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streams := maxOpenConnections + 2
+	packets := make(streamPackets, streams)
+	for i := 0; i < streams; i++ {
+		go func() {
+			_ = ds.RunStream(
+				ctx,
+				&backend.RunStreamRequest{Path: "stream/test", Data: rawQuery},
+				backend.NewStreamSender(packets),
+			)
+		}()
+	}
+	for i := 0; i < streams; i++ {
+		select {
+		case <-packets:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Received only %d of %d frames from the streams", i, streams)
+		}
+	}
+
+	queryCtx, queryCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer queryCancel()
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT count(*) AS count FROM test"})
+	response := query(dataQuery, ds.pluginConfig, ds.db, queryCtx)
+	if response.Error != nil {
+		t.Fatalf("Unexpected error while the streams are running - %s", response.Error)
+	}
+}
@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the supported units of numeric timestamps
+const (
+	epochUnitSeconds      = "s"
+	epochUnitMilliseconds = "ms"
+	epochUnitMicroseconds = "us"
+	epochUnitNanoseconds  = "ns"
+	// epochUnitJulianDay is the fractional number of days since noon in Greenwich on
+	// November 24, 4714 B.C. (as returned by the SQLite function julianday)
+	epochUnitJulianDay = "julianDay"
+)
+
+var epochUnits = map[string]time.Duration{
+	epochUnitSeconds:      time.Second,
+	epochUnitMilliseconds: time.Millisecond,
+	epochUnitMicroseconds: time.Microsecond,
+	epochUnitNanoseconds:  time.Nanosecond,
+}
+
+// unixEpochJulianDay is the julian day of the unix epoch (1970-01-01 00:00:00 UTC)
+const unixEpochJulianDay = 2440587.5
+
+// REAL values between the julian days of 0001-01-01 and 9999-12-31 are detected as julian days.
+// As unix seconds they would only cover the first two months of 1970
+const (
+	minJulianDay = 1721425.5
+	maxJulianDay = 5373484.5
+)
+
+// timeStringLayout is the layout of time values, which are converted to strings. It is the format
+// of datetime() with fractional seconds (if any)
+const timeStringLayout = "2006-01-02 15:04:05.999999999"
+
+// timeLayouts are tried in order to parse time strings. They contain the formats of the
+// SQLite date and time functions (e.g. datetime() or date())
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// timeParser converts the values of time columns to timestamps
+type timeParser struct {
+	// Layout is the (Go) layout of time strings. It is tried before the default layouts
+	Layout string
+	// EpochUnit is the unit of numeric timestamps. If empty the unit is detected by the magnitude
+	// (and REAL values in the range of julian days are julian days)
+	EpochUnit string
+	// Location is the timezone of time strings without an offset
+	Location *time.Location
+}
+
+func newTimeParser(layout string, epochUnit string, location *time.Location) (timeParser, error) {
+	if _, exists := epochUnits[epochUnit]; !exists && epochUnit != "" && epochUnit != epochUnitJulianDay {
+		return timeParser{}, fmt.Errorf("unsupported epoch unit: %s", epochUnit)
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return timeParser{Layout: layout, EpochUnit: epochUnit, Location: location}, nil
+}
+
+// unit returns the configured epoch unit or detects it by the magnitude of the value. Seconds
+// are assumed until the year 5138, then milliseconds, microseconds and nanoseconds
+func (p timeParser) unit(value float64) time.Duration {
+	if unit, exists := epochUnits[p.EpochUnit]; exists {
+		return unit
+	}
+
+	switch abs := math.Abs(value); {
+	case abs < 1e11:
+		return time.Second
+	case abs < 1e14:
+		return time.Millisecond
+	case abs < 1e17:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+func (p timeParser) parseInt(value int64) time.Time {
+	if p.EpochUnit == epochUnitJulianDay {
+		return p.parseFloat(float64(value))
+	}
+
+	unit := p.unit(float64(value))
+	perSecond := int64(time.Second / unit)
+	return time.Unix(value/perSecond, value%perSecond*int64(unit))
+}
+
+func (p timeParser) parseFloat(value float64) time.Time {
+	if p.EpochUnit == epochUnitJulianDay || p.EpochUnit == "" && isJulianDay(value) {
+		// SQLite calculates julian days with a precision of milliseconds
+		return time.UnixMilli(int64(math.Round((value - unixEpochJulianDay) * 86400000)))
+	}
+
+	unit := p.unit(value)
+	perSecond := int64(time.Second / unit)
+	whole, fraction := math.Modf(value)
+	return time.Unix(
+		int64(whole)/perSecond, int64(whole)%perSecond*int64(unit)+int64(fraction*float64(unit)),
+	)
+}
+
+// isJulianDay returns whether the value is in the range of julian days of the years 1 to 9999
+func isJulianDay(value float64) bool {
+	return value >= minJulianDay && value <= maxJulianDay
+}
+
+// formatTime formats the time as the clock reading in the location of the parser. Time strings
+// without an offset keep their value
+func (p timeParser) formatTime(value time.Time) string {
+	return value.In(p.Location).Format(timeStringLayout)
+}
+
+// parseString parses the configured layout, the default layouts and numbers (as epoch).
+// Strings without an offset are in the location of the parser
+func (p timeParser) parseString(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if p.Layout != "" {
+		if parsed, err := time.ParseInLocation(p.Layout, value, p.Location); err == nil {
+			return parsed, nil
+		}
+	}
+	for _, layout := range timeLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, p.Location); err == nil {
+			return parsed, nil
+		}
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return p.parseInt(i), nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return p.parseFloat(f), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time format: %s", value)
+}
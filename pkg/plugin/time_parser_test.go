@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeParserShouldDetectFormats(t *testing.T) {
+	parser, err := newTimeParser("", "", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	expected := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, value := range []string{
+		"2024-01-02T15:04:05Z",
+		"2024-01-02T17:04:05+02:00",
+		"2024-01-02 15:04:05",
+		"2024-01-02T15:04:05",
+		"2024-01-02 15:04:05Z",
+		"1704207845",
+		"1704207845000",
+		"1704207845000000",
+		"1704207845000000000",
+		" 2024-01-02 15:04:05 ",
+	} {
+		parsed, err := parser.parseString(value)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", value, err)
+		} else if !parsed.Equal(expected) {
+			t.Errorf("Expected %s for %s but got %s", expected, value, parsed)
+		}
+	}
+
+	for value, expected := range map[string]time.Time{
+		"2024-01-02":              time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2024-01-02 15:04":        time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+		"2024-01-02 15:04:05.123": time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		"1704207845123":           time.Date(2024, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		"1704207845.5":            time.Date(2024, 1, 2, 15, 4, 5, 500000000, time.UTC),
+	} {
+		parsed, err := parser.parseString(value)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", value, err)
+		} else if !parsed.Equal(expected) {
+			t.Errorf("Expected %s for %s but got %s", expected, value, parsed)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "2024-13-02", "NaN"} {
+		if parsed, err := parser.parseString(value); err == nil {
+			t.Errorf("Expected an error for %s but got %s", value, parsed)
+		}
+	}
+}
+
+func TestTimeParserShouldDetectJulianDays(t *testing.T) {
+	parser, err := newTimeParser("", "", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	expected := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	if parsed := parser.parseFloat(2460312.1278356481); !parsed.Equal(expected) {
+		t.Errorf("Expected %s for a julian day but got %s", expected, parsed)
+	}
+	if parsed, _ := parser.parseString("2460312.1278356481"); !parsed.Equal(expected) {
+		t.Errorf("Expected %s for a julian day string but got %s", expected, parsed)
+	}
+
+	// integers are always unix timestamps
+	expected = time.Date(1970, 1, 29, 11, 25, 12, 0, time.UTC)
+	if parsed := parser.parseInt(2460312); !parsed.Equal(expected) {
+		t.Errorf("Expected %s for an integer but got %s", expected, parsed)
+	}
+}
+
+func TestTimeParserShouldUseConfiguredUnit(t *testing.T) {
+	for _, testCase := range []struct {
+		unit     string
+		value    float64
+		expected time.Time
+	}{
+		{epochUnitSeconds, 1e12, time.Unix(1e12, 0)},
+		{epochUnitMilliseconds, 21, time.UnixMilli(21)},
+		{epochUnitMicroseconds, 21.5, time.Unix(0, 21500)},
+		{epochUnitNanoseconds, 21, time.Unix(0, 21)},
+		{epochUnitJulianDay, 2460312.12783565, time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{epochUnitJulianDay, 2440587.5, time.Unix(0, 0)},
+	} {
+		parser, err := newTimeParser("", testCase.unit, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error - %s", err)
+		}
+		if parsed := parser.parseFloat(testCase.value); !parsed.Equal(testCase.expected) {
+			t.Errorf(
+				"Expected %s for %v %s but got %s",
+				testCase.expected, testCase.value, testCase.unit, parsed,
+			)
+		}
+		if testCase.value == float64(int64(testCase.value)) {
+			if parsed := parser.parseInt(int64(testCase.value)); !parsed.Equal(testCase.expected) {
+				t.Errorf(
+					"Expected %s for the integer %v %s but got %s",
+					testCase.expected, testCase.value, testCase.unit, parsed,
+				)
+			}
+		}
+	}
+
+	if _, err := newTimeParser("", "minutes", nil); err == nil {
+		t.Error("Expected an error for an unsupported epoch unit")
+	}
+}
+
+func TestTimeParserShouldPreferConfiguredLayout(t *testing.T) {
+	parser, err := newTimeParser("02.01.2006 15:04", "", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error - %s", err)
+	}
+
+	for value, expected := range map[string]time.Time{
+		"02.01.2024 15:04":     time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+		"2024-01-02T15:04:05Z": time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+	} {
+		parsed, err := parser.parseString(value)
+		if err != nil {
+			t.Errorf("Unexpected error for %s - %s", value, err)
+		} else if !parsed.Equal(expected) {
+			t.Errorf("Expected %s for %s but got %s", expected, value, parsed)
+		}
+	}
+}
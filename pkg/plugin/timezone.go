@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// location returns the configured timezone of time strings without an offset (UTC by default)
+func (config pluginConfig) location() (*time.Location, error) {
+	if config.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported timezone '%s': %w", config.Timezone, err)
+	}
+	return location, nil
+}
+
+// wallClock returns the time in UTC, which has the same clock reading as the time in the
+// location. SQLite treats time strings without an offset as UTC, so the time range needs to be
+// shifted to compare it with such strings
+func wallClock(t time.Time, location *time.Location) time.Time {
+	_, offset := t.In(location).Zone()
+	return t.UTC().Add(time.Duration(offset) * time.Second)
+}
+
+// fromWallClock is the inverse of wallClock
+func fromWallClock(t time.Time, location *time.Location) time.Time {
+	t = t.UTC()
+	return time.Date(
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location,
+	)
+}
+
+// nextWallClockBucket adds the interval to the clock reading of the time in the location. The
+// result is always after the given time, even if the clock is turned back (e.g. at the end of
+// daylight saving time)
+func nextWallClockBucket(t time.Time, interval time.Duration, location *time.Location) time.Time {
+	clock := wallClock(t, location)
+	for {
+		clock = clock.Add(interval)
+		if next := fromWallClock(clock, location); next.After(t) {
+			return next
+		}
+	}
+}
+
+// wallClockToUnix converts an SQL expression of clock readings in the location (as unix seconds)
+// to actual unix seconds. SQLite does not know the offsets of the location, so every offset
+// within the time range (extended by the margin) is added as a case of the expression
+func wallClockToUnix(
+	expression string, timeRange backend.TimeRange, margin time.Duration, location *time.Location,
+) string {
+	end := timeRange.To.Add(margin)
+	cases := []string{}
+	current := timeRange.From.Add(-margin)
+	for {
+		_, offset := current.In(location).Zone()
+		_, transition := current.In(location).ZoneBounds()
+
+		if transition.IsZero() || transition.After(end) {
+			if len(cases) == 0 {
+				return shiftSeconds(expression, -offset)
+			}
+			return fmt.Sprintf(
+				"CASE %s ELSE %s END", strings.Join(cases, " "), shiftSeconds(expression, -offset),
+			)
+		}
+
+		// the clock reading of the transition is based on the offset before the transition
+		cases = append(cases, fmt.Sprintf(
+			"WHEN %s < %d THEN %s",
+			expression, transition.Unix()+int64(offset), shiftSeconds(expression, -offset),
+		))
+		current = transition
+	}
+}
+
+func shiftSeconds(expression string, seconds int) string {
+	switch {
+	case seconds > 0:
+		return fmt.Sprintf("%s + %d", expression, seconds)
+	case seconds < 0:
+		return fmt.Sprintf("%s - %d", expression, -seconds)
+	default:
+		return expression
+	}
+}
@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// tracesType is the query type of trace queries. The columns with the following names are
+// converted to the trace format of Grafana. All other columns are tags of the spans
+const tracesType = "traces"
+
+const (
+	traceIDColumn       = "traceID"
+	spanIDColumn        = "spanID"
+	parentSpanIDColumn  = "parentSpanID"
+	operationNameColumn = "operationName"
+	serviceNameColumn   = "serviceName"
+	// serviceTagsColumn is a JSON object with the tags of the service
+	serviceTagsColumn = "serviceTags"
+	// startTimeColumn is converted like time columns
+	startTimeColumn = "startTime"
+	// durationColumn is the duration of the span in milliseconds
+	durationColumn      = "duration"
+	kindColumn          = "kind"
+	statusCodeColumn    = "statusCode"
+	statusMessageColumn = "statusMessage"
+)
+
+// traceColumnTypes are the types of the trace columns, which are converted
+var traceColumnTypes = map[string]string{
+	traceIDColumn:       "string",
+	spanIDColumn:        "string",
+	parentSpanIDColumn:  "string",
+	operationNameColumn: "string",
+	serviceNameColumn:   "string",
+	serviceTagsColumn:   "string",
+	startTimeColumn:     "time",
+	durationColumn:      "float",
+	kindColumn:          "string",
+	statusCodeColumn:    "int",
+	statusMessageColumn: "string",
+}
+
+// traceTag is a key value pair of the tags of a span (or its service)
+type traceTag struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// prepareTracesQuery sets the types of the trace columns
+func prepareTracesQuery(queryConfig *queryConfigStruct) {
+	queryConfig.TimeColumns = append(slices.Clone(queryConfig.TimeColumns), startTimeColumn)
+
+	columnTypes := maps.Clone(queryConfig.ColumnTypes)
+	if columnTypes == nil {
+		columnTypes = map[string]string{}
+	}
+	maps.Copy(columnTypes, traceColumnTypes)
+	queryConfig.ColumnTypes = columnTypes
+}
+
+// tracesFrame creates a frame in the trace format of Grafana. Each row is a span. Spans without
+// a span ID or a start time are skipped
+func tracesFrame(columns []*sqlColumn, queryConfig *queryConfigStruct) (*data.Frame, error) {
+	traceColumns := map[string]*sqlColumn{}
+	tagColumns := []*sqlColumn{}
+	for _, column := range columns {
+		if _, isTraceColumn := traceColumnTypes[column.Name]; isTraceColumn {
+			traceColumns[column.Name] = column
+		} else {
+			tagColumns = append(tagColumns, column)
+		}
+	}
+	for _, name := range []string{traceIDColumn, spanIDColumn, startTimeColumn, durationColumn} {
+		if traceColumns[name] == nil {
+			return nil, fmt.Errorf(
+				"a traces query needs the columns %s, %s, %s and %s",
+				traceIDColumn, spanIDColumn, startTimeColumn, durationColumn,
+			)
+		}
+	}
+
+	stringValue := func(name string, row int) *string {
+		if column := traceColumns[name]; column != nil {
+			return rowValue(column.StringData, row)
+		}
+		return nil
+	}
+	valueOrEmpty := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return *value
+	}
+
+	frame := data.NewFrame(
+		"Trace",
+		data.NewField(traceIDColumn, nil, []string{}),
+		data.NewField(spanIDColumn, nil, []string{}),
+		data.NewField(parentSpanIDColumn, nil, []*string{}),
+		data.NewField(operationNameColumn, nil, []string{}),
+		data.NewField(serviceNameColumn, nil, []string{}),
+		data.NewField(serviceTagsColumn, nil, []json.RawMessage{}),
+		data.NewField(startTimeColumn, nil, []float64{}),
+		data.NewField(durationColumn, nil, []float64{}),
+		data.NewField("tags", nil, []json.RawMessage{}),
+	)
+	optionalFields := map[string]*data.Field{}
+	if traceColumns[kindColumn] != nil {
+		optionalFields[kindColumn] = data.NewField(kindColumn, nil, []*string{})
+	}
+	if traceColumns[statusCodeColumn] != nil {
+		optionalFields[statusCodeColumn] = data.NewField(statusCodeColumn, nil, []*int64{})
+	}
+	if traceColumns[statusMessageColumn] != nil {
+		optionalFields[statusMessageColumn] = data.NewField(statusMessageColumn, nil, []*string{})
+	}
+
+	startTimes := traceColumns[startTimeColumn].TimeData
+	for row := range startTimes {
+		spanID := stringValue(spanIDColumn, row)
+		if spanID == nil || startTimes[row] == nil {
+			continue
+		}
+
+		parentSpanID := stringValue(parentSpanIDColumn, row)
+		if parentSpanID != nil && *parentSpanID == "" {
+			parentSpanID = nil
+		}
+		duration := 0.0
+		if value := rowValue(traceColumns[durationColumn].FloatData, row); value != nil {
+			duration = *value
+		}
+
+		serviceTags := []traceTag{}
+		if column := traceColumns[serviceTagsColumn]; column != nil {
+			serviceTags = columnTags(column, row)
+		}
+		tags := []traceTag{}
+		for _, column := range tagColumns {
+			tags = append(tags, columnTags(column, row)...)
+		}
+		rawServiceTags, err := json.Marshal(serviceTags)
+		if err != nil {
+			return nil, err
+		}
+		rawTags, err := json.Marshal(tags)
+		if err != nil {
+			return nil, err
+		}
+
+		frame.AppendRow(
+			valueOrEmpty(stringValue(traceIDColumn, row)),
+			*spanID,
+			parentSpanID,
+			valueOrEmpty(stringValue(operationNameColumn, row)),
+			valueOrEmpty(stringValue(serviceNameColumn, row)),
+			json.RawMessage(rawServiceTags),
+			// milliseconds with a precision of microseconds
+			float64(startTimes[row].UnixMicro())/1000,
+			duration,
+			json.RawMessage(rawTags),
+		)
+		if field := optionalFields[kindColumn]; field != nil {
+			field.Append(stringValue(kindColumn, row))
+		}
+		if field := optionalFields[statusCodeColumn]; field != nil {
+			field.Append(rowValue(traceColumns[statusCodeColumn].IntData, row))
+		}
+		if field := optionalFields[statusMessageColumn]; field != nil {
+			field.Append(stringValue(statusMessageColumn, row))
+		}
+	}
+
+	for _, name := range []string{kindColumn, statusCodeColumn, statusMessageColumn} {
+		if field := optionalFields[name]; field != nil {
+			frame.Fields = append(frame.Fields, field)
+		}
+	}
+
+	frame.Meta = queryConfig.frameMeta()
+	frame.Meta.PreferredVisualization = data.VisTypeTrace
+
+	return frame, nil
+}
+
+// columnTags returns the tags of a column. JSON objects (e.g. the attributes of a span) are
+// decoded into one tag per key. Other values are a tag named after the column
+func columnTags(column *sqlColumn, row int) []traceTag {
+	value := columnValueString(column, row)
+	if value == nil {
+		return []traceTag{}
+	}
+
+	if column.Type == "STRING" || column.Type == "JSON" {
+		var attributes map[string]interface{}
+		if strings.HasPrefix(strings.TrimSpace(*value), "{") &&
+			json.Unmarshal([]byte(*value), &attributes) == nil {
+			tags := []traceTag{}
+			for _, key := range slices.Sorted(maps.Keys(attributes)) {
+				tags = append(tags, traceTag{Key: key, Value: attributes[key]})
+			}
+			return tags
+		}
+	}
+
+	switch column.Type {
+	case "INTEGER":
+		return []traceTag{{Key: column.Name, Value: *rowValue(column.IntData, row)}}
+	case "FLOAT":
+		return []traceTag{{Key: column.Name, Value: *rowValue(column.FloatData, row)}}
+	case "BOOL":
+		return []traceTag{{Key: column.Name, Value: *rowValue(column.BoolData, row)}}
+	default:
+		return []traceTag{{Key: column.Name, Value: *value}}
+	}
+}
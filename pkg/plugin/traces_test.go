@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTracesQuery(t *testing.T) {
+	dbPath, cleanup := createTmpDB(`
+		CREATE TABLE spans(
+			trace_id TEXT, span_id TEXT, parent_span_id TEXT, service TEXT, operation TEXT,
+			start INTEGER, duration_ns INTEGER, attributes TEXT, retries INTEGER
+		);
+		INSERT INTO spans VALUES
+			('t1', 's1', NULL, 'api', 'GET /', 1612325106000, 50000000, '{"http.status": 200, "http.method": "GET"}', 0),
+			('t1', 's2', 's1', 'db', 'SELECT', 1612325106010, 2500000, NULL, NULL),
+			('t1', NULL, 's1', 'db', 'broken', 1612325106020, 1000000, NULL, NULL);
+	`)
+	defer cleanup()
+
+	queryText := `
+		SELECT trace_id AS traceID, span_id AS spanID, parent_span_id AS parentSpanID,
+			service AS serviceName, operation AS operationName, start AS startTime,
+			duration_ns / 1000000.0 AS duration, attributes, retries
+		FROM spans
+	`
+	dataQuery := getDataQuery(queryModel{QueryText: queryText})
+	dataQuery.QueryType = tracesType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error != nil {
+		t.Fatalf("Unexpected error - %s", response.Error)
+	}
+	if len(response.Frames) != 1 {
+		t.Fatalf("Expected one frame but got %d", len(response.Frames))
+	}
+
+	expectedFrame := data.NewFrame(
+		"Trace",
+		data.NewField("traceID", nil, []string{"t1", "t1"}),
+		data.NewField("spanID", nil, []string{"s1", "s2"}),
+		data.NewField("parentSpanID", nil, []*string{nil, strPointer("s1")}),
+		data.NewField("operationName", nil, []string{"GET /", "SELECT"}),
+		data.NewField("serviceName", nil, []string{"api", "db"}),
+		data.NewField("serviceTags", nil, []json.RawMessage{
+			json.RawMessage(`[]`), json.RawMessage(`[]`),
+		}),
+		data.NewField("startTime", nil, []float64{1612325106000, 1612325106010}),
+		data.NewField("duration", nil, []float64{50, 2.5}),
+		data.NewField("tags", nil, []json.RawMessage{
+			json.RawMessage(
+				`[{"key":"http.method","value":"GET"},{"key":"http.status","value":200},` +
+					`{"key":"retries","value":0}]`,
+			),
+			json.RawMessage(`[]`),
+		}),
+	)
+	expectedFrame.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeTrace,
+		ExecutedQueryString:    queryText,
+	}
+
+	if diff := cmp.Diff(expectedFrame, response.Frames[0], cmpOption...); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestTracesQueryShouldRequireTheSpanColumns(t *testing.T) {
+	dbPath, cleanup := createTmpDB("SELECT 1 -- create db")
+	defer cleanup()
+
+	dataQuery := getDataQuery(queryModel{QueryText: "SELECT 't1' AS traceID, 's1' AS spanID"})
+	dataQuery.QueryType = tracesType
+
+	response := runQuery(t, dataQuery, pluginConfig{Path: dbPath})
+	if response.Error == nil || !strings.Contains(response.Error.Error(), "a traces query needs the columns") {
+		t.Errorf("Expected a missing column error but got %v", response.Error)
+	}
+}
@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inferColumnTypes sets the type of the columns without a type (e.g. computed columns) to the
+// common type of all their values. Integers are widened to floats and numbers to strings. With
+// inferTimes text columns are time columns if all values are times or have a time format. Columns
+// with only NULL values keep the unknown type
+func inferColumnTypes(
+	columns []*sqlColumn, rowValues [][]interface{}, parser timeParser, inferTimes bool,
+) {
+	for idx, column := range columns {
+		if column.Type != "UNKNOWN" {
+			continue
+		}
+
+		columnType := ""
+		allTimes := true
+		for _, values := range rowValues {
+			if values[idx] == nil {
+				continue
+			}
+			// the driver already parses the values of DATE, DATETIME and TIMESTAMP columns
+			_, isTime := values[idx].(time.Time)
+			text, isText := values[idx].(string)
+			allTimes = allTimes && (isTime || isText && isTimeString(text, parser))
+
+			valueType := ""
+			switch v := values[idx].(type) {
+			case int8, int16, int32, int64, int, uint8, uint16, uint32:
+				valueType = "INTEGER"
+			case uint64:
+				valueType = "INTEGER"
+				if v > math.MaxInt64 {
+					valueType = "FLOAT"
+				}
+			case float32, float64:
+				valueType = "FLOAT"
+			case []byte:
+				valueType = "STRING"
+				if column.BlobFormat == blobFormatLength {
+					valueType = "INTEGER"
+				}
+			default:
+				valueType = "STRING"
+			}
+
+			columnType = widerType(columnType, valueType)
+		}
+
+		switch {
+		case columnType == "":
+			// the column only has NULL values
+		case columnType == "STRING" && allTimes && inferTimes && !column.Raw:
+			column.Type = "TIME"
+		default:
+			column.Type = columnType
+		}
+	}
+}
+
+// widerType returns the type, which can hold the values of both types (int ⊂ float ⊂ string)
+func widerType(a string, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case a == "STRING" || b == "STRING":
+		return "STRING"
+	default:
+		return "FLOAT"
+	}
+}
+
+// isTimeString returns whether the value is a time in a supported format. Numbers are no times,
+// as they are more likely values than timestamps
+func isTimeString(value string, parser timeParser) bool {
+	if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+		return false
+	}
+	_, err := parser.parseString(value)
+	return err == nil
+}
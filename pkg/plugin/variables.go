@@ -3,8 +3,10 @@ package plugin
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 )
 
 // replaceVariables replaces Grafana Template Variables in the query
@@ -20,5 +22,36 @@ func replaceVariables(queryConfig *queryConfigStruct, dataQuery backend.DataQuer
 		"$__to",
 		strconv.FormatInt(dataQuery.TimeRange.To.Unix()*1000, 10),
 	)
+
+	interval := queryInterval(dataQuery)
+	if interval > 0 {
+		// $__interval_ms needs to be replaced first as $__interval is a prefix of it
+		queryConfig.FinalQuery = strings.ReplaceAll(
+			queryConfig.FinalQuery,
+			"$__interval_ms",
+			strconv.FormatInt(interval.Milliseconds(), 10),
+		)
+		queryConfig.FinalQuery = strings.ReplaceAll(
+			queryConfig.FinalQuery,
+			"$__interval",
+			gtime.FormatInterval(interval),
+		)
+	}
 	return nil
 }
+
+// queryInterval returns the interval of the query. If Grafana did not send an interval it is
+// derived from the time range and the maximum number of data points (as Grafana does)
+func queryInterval(dataQuery backend.DataQuery) time.Duration {
+	if dataQuery.Interval > 0 {
+		return dataQuery.Interval
+	}
+
+	if dataQuery.MaxDataPoints > 0 {
+		return gtime.RoundInterval(
+			dataQuery.TimeRange.Duration() / time.Duration(dataQuery.MaxDataPoints),
+		)
+	}
+
+	return 0
+}
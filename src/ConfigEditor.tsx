@@ -1,10 +1,18 @@
-import { DataSourcePluginOptionsEditorProps } from '@grafana/data';
+import { DataSourcePluginOptionsEditorProps, SelectableValue } from '@grafana/data';
 import { Alert, LegacyForms } from '@grafana/ui';
 import React, { ChangeEvent, PureComponent } from 'react';
 
-import { MyDataSourceOptions, MySecureJsonData } from './types';
+import { EpochUnit, MyDataSourceOptions, MySecureJsonData } from './types';
 
-const { FormField } = LegacyForms;
+const { FormField, Select } = LegacyForms;
+
+const epochUnitOptions: Array<SelectableValue<EpochUnit>> = [
+  { label: 'Seconds', value: 's' },
+  { label: 'Milliseconds', value: 'ms' },
+  { label: 'Microseconds', value: 'us' },
+  { label: 'Nanoseconds', value: 'ns' },
+  { label: 'Julian day', value: 'julianDay' },
+];
 
 interface Props extends DataSourcePluginOptionsEditorProps<MyDataSourceOptions, MySecureJsonData> {}
 
@@ -48,6 +56,33 @@ export class ConfigEditor extends PureComponent<Props, State> {
     onOptionsChange({ ...options, jsonData });
   };
 
+  onTimeFormatChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      timeFormat: event.target.value || undefined,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  onTimezoneChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      timezone: event.target.value || undefined,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
+  onEpochUnitChange = (option: SelectableValue<EpochUnit> | null) => {
+    const { onOptionsChange, options } = this.props;
+    const jsonData = {
+      ...options.jsonData,
+      epochUnit: option?.value,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
   onAttachLimitChange = (event: ChangeEvent<HTMLInputElement>) => {
     let value: number | undefined = undefined;
 
@@ -67,6 +102,26 @@ export class ConfigEditor extends PureComponent<Props, State> {
     onOptionsChange({ ...options, jsonData });
   };
 
+  onOptionalNumberChange =
+    (key: 'maxConcurrentQueries' | 'queryTimeout' | 'maxRows' | 'maxBlobSize') => (event: ChangeEvent<HTMLInputElement>) => {
+      let value: number | undefined = undefined;
+
+      if (event.target.value !== '') {
+        value = parseInt(event.target.value, 10);
+        if (Number.isNaN(value)) {
+          return;
+        }
+      }
+
+      const { onOptionsChange, options } = this.props;
+      const jsonData = {
+        ...options.jsonData,
+        [key]: value,
+      };
+
+      onOptionsChange({ ...options, jsonData });
+    };
+
   render() {
     const { options, onOptionsChange } = this.props;
     const { jsonData, secureJsonFields, secureJsonData } = options;
@@ -158,6 +213,108 @@ export class ConfigEditor extends PureComponent<Props, State> {
             onChange={this.onAttachLimitChange}
           />
         </div>
+        <div className="gf-form">
+          <FormField
+            label="Max concurrent queries"
+            tooltip={
+              'The number of queries of a single panel or request that are executed in parallel. ' +
+              'Leave empty to use the default of 4.'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.maxConcurrentQueries}
+            onChange={this.onOptionalNumberChange('maxConcurrentQueries')}
+            placeholder="4"
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Query timeout"
+            tooltip={
+              'The maximum time in seconds a query may run before it is interrupted. ' +
+              'Queries can only lower this value. Leave empty for no timeout.'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.queryTimeout}
+            onChange={this.onOptionalNumberChange('queryTimeout')}
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Max rows"
+            tooltip={
+              'The maximum number of rows returned by a query. Larger results are truncated. ' +
+              'Queries can only lower this value. Leave empty for no limit.'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.maxRows}
+            onChange={this.onOptionalNumberChange('maxRows')}
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Max BLOB size"
+            tooltip={
+              'The maximum number of bytes of BLOB values. Larger values are truncated. ' +
+              'Leave empty for the default of 1 MiB.'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.maxBlobSize}
+            onChange={this.onOptionalNumberChange('maxBlobSize')}
+            placeholder="1048576"
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Time format"
+            tooltip={
+              'The layout of time strings in a format other than RFC3339 or the SQLite date and time functions. ' +
+              'The layout uses the reference time of Go, e.g. "02.01.2006 15:04:05".'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.timeFormat || ''}
+            onChange={this.onTimeFormatChange}
+            placeholder="auto-detect"
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Epoch unit"
+            tooltip={
+              'The unit of numeric timestamps. By default the unit (seconds up to nanoseconds) is detected by the ' +
+              'magnitude of the value. Decimal numbers in the range of Julian days are detected as Julian days.'
+            }
+            labelWidth={10}
+            inputEl={
+              <Select
+                width={20}
+                isClearable
+                placeholder="auto-detect"
+                options={epochUnitOptions}
+                value={epochUnitOptions.find((option) => option.value === jsonData.epochUnit) || null}
+                onChange={this.onEpochUnitChange}
+              />
+            }
+          />
+        </div>
+        <div className="gf-form">
+          <FormField
+            label="Timezone"
+            tooltip={
+              'The timezone of time strings without an offset, e.g. the results of datetime() or CURRENT_TIMESTAMP. ' +
+              'Use an IANA name like "Europe/Berlin". Leave empty for UTC.'
+            }
+            labelWidth={10}
+            inputWidth={20}
+            value={jsonData.timezone || ''}
+            onChange={this.onTimezoneChange}
+            placeholder="UTC"
+          />
+        </div>
         <div className="gf-form">
           <Alert title="File System Permissions" severity="info">
             <div>
@@ -1,22 +1,183 @@
-import { DataSourceInstanceSettings, DataFrame, ScopedVars } from '@grafana/data';
-import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
-import { MyDataSourceOptions, SQLiteQuery } from './types';
+import {
+  AnnotationQuery,
+  DataFrame,
+  DataQueryRequest,
+  DataQueryResponse,
+  DataSourceInstanceSettings,
+  DataSourceWithLogsContextSupport,
+  DataSourceWithSupplementaryQueriesSupport,
+  dateTime,
+  LiveChannelScope,
+  LogRowContextOptions,
+  LogRowContextQueryDirection,
+  LogRowModel,
+  ScopedVars,
+  SupplementaryQueryOptions,
+  SupplementaryQueryType,
+} from '@grafana/data';
+import { DataSourceWithBackend, getGrafanaLiveSrv, getTemplateSrv } from '@grafana/runtime';
+import { lastValueFrom, merge, Observable } from 'rxjs';
+import { MyDataSourceOptions, SchemaColumn, SchemaIndex, SchemaTable, SchemaView, SQLiteQuery } from './types';
 
-export class DataSource extends DataSourceWithBackend<SQLiteQuery, MyDataSourceOptions> {
+// hashQuery creates a short identifier of a streaming query. Panels with the same query share a stream
+function hashQuery(value: string): string {
+  let hash = 0;
+  for (let idx = 0; idx < value.length; idx++) {
+    hash = (hash * 31 + value.charCodeAt(idx)) | 0;
+  }
+  return (hash >>> 0).toString(16);
+}
+
+// the time range before or after a log line, which is queried for the log context
+const logContextRange = 24 * 60 * 60 * 1000;
+
+export class DataSource
+  extends DataSourceWithBackend<SQLiteQuery, MyDataSourceOptions>
+  implements DataSourceWithLogsContextSupport<SQLiteQuery>, DataSourceWithSupplementaryQueriesSupport<SQLiteQuery>
+{
   templateSrv;
 
   constructor(instanceSettings: DataSourceInstanceSettings<MyDataSourceOptions>) {
     super(instanceSettings);
 
     this.templateSrv = getTemplateSrv();
-    this.annotations = {};
+    this.annotations = {
+      // the backend converts the time, timeEnd, text and tags columns of annotation queries
+      prepareQuery: (annotation: AnnotationQuery<SQLiteQuery>) =>
+        annotation.target ? { ...annotation.target, queryType: 'annotation' } : undefined,
+    };
+  }
+
+  query(request: DataQueryRequest<SQLiteQuery>): Observable<DataQueryResponse> {
+    const streamingTargets = request.targets.filter((target) => !target.hide && target.streamKeyColumn);
+    if (streamingTargets.length === 0) {
+      return super.query(request);
+    }
+
+    const responses: Array<Observable<DataQueryResponse>> = streamingTargets.map((target) => {
+      const query = this.applyTemplateVariables({ ...target }, request.scopedVars);
+      const data = {
+        queryText: query.queryText,
+        timeColumns: query.timeColumns,
+        boolColumns: query.boolColumns,
+        columnTypes: query.columnTypes,
+        blobFormats: query.blobFormats,
+        timeFormat: query.timeFormat,
+        epochUnit: query.epochUnit,
+        editorMode: query.editorMode,
+        builder: query.builder,
+        queryTimeout: query.queryTimeout,
+        maxRows: query.maxRows,
+        streamKeyColumn: query.streamKeyColumn,
+        streamInterval: query.streamInterval,
+        from: request.range.from.valueOf(),
+      };
+
+      return getGrafanaLiveSrv().getDataStream({
+        key: `${request.requestId}.${target.refId}`,
+        addr: {
+          scope: LiveChannelScope.DataSource,
+          namespace: this.uid,
+          path: `stream/${hashQuery(JSON.stringify(data))}`,
+          data,
+        },
+      });
+    });
+
+    const otherTargets = request.targets.filter((target) => !target.streamKeyColumn);
+    if (otherTargets.length > 0) {
+      responses.push(super.query({ ...request, targets: otherTargets }));
+    }
+
+    return merge(...responses);
+  }
+
+  getSupportedSupplementaryQueryTypes(): SupplementaryQueryType[] {
+    return [SupplementaryQueryType.LogsVolume];
+  }
+
+  // the logs volume counts the log lines of a logs query per level in the backend
+  getSupplementaryQuery(options: SupplementaryQueryOptions, query: SQLiteQuery): SQLiteQuery | undefined {
+    if (options.type !== SupplementaryQueryType.LogsVolume || query.queryType !== 'logs') {
+      return undefined;
+    }
+    return { ...query, refId: `log-volume-${query.refId}`, queryType: 'logs volume' };
+  }
+
+  getSupplementaryRequest(
+    type: SupplementaryQueryType,
+    request: DataQueryRequest<SQLiteQuery>
+  ): DataQueryRequest<SQLiteQuery> | undefined {
+    const targets = request.targets
+      .filter((target) => !target.hide)
+      .map((target) => this.getSupplementaryQuery({ type }, target))
+      .filter((target): target is SQLiteQuery => target !== undefined);
+    if (targets.length === 0) {
+      return undefined;
+    }
+    return { ...request, targets };
+  }
+
+  // the log context queries the log lines before or after a log line with the logs query of the line
+  getLogRowContext(
+    row: LogRowModel,
+    options?: LogRowContextOptions,
+    query?: SQLiteQuery
+  ): Promise<{ data: DataFrame[] }> {
+    if (!query) {
+      return Promise.reject(new Error('The log context needs the query of the log line'));
+    }
+
+    const backward = options?.direction !== LogRowContextQueryDirection.Forward;
+    const from = dateTime(backward ? row.timeEpochMs - logContextRange : row.timeEpochMs);
+    const to = dateTime(backward ? row.timeEpochMs : row.timeEpochMs + logContextRange);
+    const request = {
+      targets: [
+        {
+          ...query,
+          refId: `log-context-${query.refId}`,
+          logContext: {
+            time: row.timeEpochMs,
+            direction: backward ? 'backward' : 'forward',
+            limit: options?.limit ?? 50,
+          },
+        },
+      ],
+      range: { from, to, raw: { from, to } },
+      scopedVars: {},
+    } as any;
+
+    return lastValueFrom(this.query(request));
   }
 
   applyTemplateVariables(query: SQLiteQuery, scopedVars: ScopedVars): SQLiteQuery {
     query.queryText = this.templateSrv.replace(query.rawQueryText, scopedVars);
+    if (query.rawEdgesQueryText) {
+      query.edgesQueryText = this.templateSrv.replace(query.rawEdgesQueryText, scopedVars);
+    }
     return query;
   }
 
+  getTables(): Promise<SchemaTable[]> {
+    return this.getResource('tables');
+  }
+
+  getColumns(table: string): Promise<SchemaColumn[]> {
+    return this.getResource(`tables/${encodeURIComponent(table)}/columns`);
+  }
+
+  getAllColumns(): Promise<Record<string, SchemaColumn[]>> {
+    return this.getResource('columns');
+  }
+
+  getIndexes(table?: string): Promise<SchemaIndex[]> {
+    return this.getResource('indexes', table ? { table } : undefined);
+  }
+
+  getViews(): Promise<SchemaView[]> {
+    return this.getResource('views');
+  }
+
   async metricFindQuery(query: string, options?: any) {
     if (!query) {
       return [];
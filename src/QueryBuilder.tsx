@@ -0,0 +1,310 @@
+import { SelectableValue } from '@grafana/data';
+import {
+  Button,
+  IconButton,
+  InlineField,
+  InlineFieldRow,
+  Input,
+  MultiSelect,
+  RadioButtonGroup,
+  Select,
+} from '@grafana/ui';
+import React, { useEffect, useState } from 'react';
+
+import { DataSource } from './DataSource';
+import { BuilderAggregation, BuilderOperator, BuilderQuery } from './types';
+
+type BuilderFilter = NonNullable<BuilderQuery['filters']>[number];
+
+interface Props {
+  datasource: DataSource;
+  builder: BuilderQuery;
+  onChange: (builder: BuilderQuery) => void;
+}
+
+const aggregationOptions: Array<SelectableValue<BuilderAggregation>> = ['count', 'sum', 'avg', 'min', 'max'].map(
+  (aggregation) => ({ label: aggregation, value: aggregation as BuilderAggregation })
+);
+
+const operatorOptions: Array<SelectableValue<BuilderOperator>> = (
+  ['=', '!=', '<', '<=', '>', '>=', 'LIKE', 'NOT LIKE', 'IN', 'NOT IN', 'IS NULL', 'IS NOT NULL'] as BuilderOperator[]
+).map((operator) => ({ label: operator, value: operator }));
+
+const timeFormatOptions: Array<SelectableValue<'iso' | 'unix' | 'unixMs'>> = [
+  { label: 'ISO string', value: 'iso' },
+  { label: 'Unix seconds', value: 'unix' },
+  { label: 'Unix milliseconds', value: 'unixMs' },
+];
+
+const directionOptions: Array<SelectableValue<'ASC' | 'DESC'>> = [
+  { label: 'ASC', value: 'ASC' },
+  { label: 'DESC', value: 'DESC' },
+];
+
+// selected values are options, also if they were entered as custom value
+function toOption(value?: string): SelectableValue<string> | null {
+  return value ? { label: value, value } : null;
+}
+
+// the value of a filter as entered in the editor. Lists (of IN filters) are comma separated
+function formatFilterValue(value: BuilderFilter['value']): string {
+  if (Array.isArray(value)) {
+    return value.join(', ');
+  }
+  return value === undefined || value === null ? '' : String(value);
+}
+
+// filter values are strings, SQLite converts them for comparisons with numeric columns
+function parseFilterValue(operator: BuilderOperator, text: string): BuilderFilter['value'] {
+  if (operator === 'IN' || operator === 'NOT IN') {
+    return text
+      .split(',')
+      .map((item) => item.trim())
+      .filter((item) => item !== '');
+  }
+  return text;
+}
+
+// isBuilderComplete returns whether the builder query can be compiled by the backend
+export function isBuilderComplete(builder: BuilderQuery): boolean {
+  return (
+    Boolean(builder.table) &&
+    (builder.columns || []).every((column) => column.name) &&
+    (builder.filters || []).every((filter) => filter.column) &&
+    (builder.orderBy || []).every((order) => order.column)
+  );
+}
+
+/**
+ * The visual query builder. The backend compiles the builder model into SQL.
+ * Rows are keyed by their count, so that their inputs are reset after a row was removed.
+ */
+export function QueryBuilder({ datasource, builder, onChange }: Props) {
+  const [tables, setTables] = useState<Array<SelectableValue<string>>>([]);
+  const [columns, setColumns] = useState<Array<SelectableValue<string>>>([]);
+
+  useEffect(() => {
+    datasource
+      ?.getTables()
+      .then((result) => setTables(result.map((table) => ({ label: table.name, value: table.name }))))
+      .catch(() => setTables([]));
+  }, [datasource]);
+
+  useEffect(() => {
+    if (!builder.table) {
+      setColumns([]);
+      return;
+    }
+    datasource
+      ?.getColumns(builder.table)
+      .then((result) =>
+        setColumns(result.map((column) => ({ label: column.name, value: column.name, description: column.type })))
+      )
+      .catch(() => setColumns([]));
+  }, [datasource, builder.table]);
+
+  const selectedColumns = builder.columns || [];
+  const filters = builder.filters || [];
+  const orders = builder.orderBy || [];
+
+  function update(changes: Partial<BuilderQuery>) {
+    onChange({ ...builder, ...changes });
+  }
+
+  function updateItem<T>(items: T[], index: number, changes: Partial<T>): T[] {
+    return items.map((item, idx) => (idx === index ? { ...item, ...changes } : item));
+  }
+
+  function removeItem<T>(items: T[], index: number): T[] {
+    return items.filter((_, idx) => idx !== index);
+  }
+
+  return (
+    <div role="query-builder">
+      <InlineFieldRow>
+        <InlineField label="Table" labelWidth={16}>
+          <Select
+            width={30}
+            allowCustomValue
+            options={tables}
+            value={toOption(builder.table)}
+            placeholder="Select table"
+            onChange={(option) => update({ table: option?.value || '' })}
+          />
+        </InlineField>
+        <InlineField
+          label="Time column"
+          labelWidth={16}
+          tooltip="Selected first, formatted as time and filtered by the time range"
+        >
+          <Select
+            width={30}
+            allowCustomValue
+            isClearable
+            options={columns}
+            value={toOption(builder.timeColumn)}
+            placeholder="none"
+            onChange={(option) => update({ timeColumn: option?.value || undefined })}
+          />
+        </InlineField>
+        {builder.timeColumn && (
+          <InlineField label="Stored as">
+            <RadioButtonGroup
+              options={timeFormatOptions}
+              value={builder.timeFormat || 'iso'}
+              onChange={(value) => update({ timeFormat: value })}
+            />
+          </InlineField>
+        )}
+      </InlineFieldRow>
+      {selectedColumns.map((column, index) => (
+        <InlineFieldRow key={`column-${index}-${selectedColumns.length}`}>
+          <InlineField label={index === 0 ? 'Columns' : ''} labelWidth={16}>
+            <Select
+              width={30}
+              allowCustomValue
+              options={[{ label: '*', value: '*' }, ...columns]}
+              value={toOption(column.name)}
+              onChange={(option) =>
+                update({ columns: updateItem(selectedColumns, index, { name: option?.value || '' }) })
+              }
+            />
+          </InlineField>
+          <InlineField label="Aggregation">
+            <Select
+              width={15}
+              isClearable
+              options={aggregationOptions}
+              value={column.aggregation || null}
+              placeholder="none"
+              onChange={(option) =>
+                update({ columns: updateItem(selectedColumns, index, { aggregation: option?.value || undefined }) })
+              }
+            />
+          </InlineField>
+          <InlineField label="Alias">
+            <Input
+              width={20}
+              defaultValue={column.alias}
+              onBlur={(event) =>
+                update({
+                  columns: updateItem(selectedColumns, index, { alias: event.currentTarget.value || undefined }),
+                })
+              }
+            />
+          </InlineField>
+          <IconButton
+            name="trash-alt"
+            aria-label="Remove column"
+            onClick={() => update({ columns: removeItem(selectedColumns, index) })}
+          />
+        </InlineFieldRow>
+      ))}
+      {filters.map((filter, index) => (
+        <InlineFieldRow key={`filter-${index}-${filters.length}`}>
+          <InlineField label={index === 0 ? 'Filters' : 'AND'} labelWidth={16}>
+            <Select
+              width={30}
+              allowCustomValue
+              options={columns}
+              value={toOption(filter.column)}
+              onChange={(option) => update({ filters: updateItem(filters, index, { column: option?.value || '' }) })}
+            />
+          </InlineField>
+          <Select
+            width={15}
+            options={operatorOptions}
+            value={filter.operator}
+            onChange={(option) => {
+              const operator = option.value || '=';
+              const value = parseFilterValue(operator, formatFilterValue(filter.value));
+              update({ filters: updateItem(filters, index, { operator, value }) });
+            }}
+          />
+          {filter.operator !== 'IS NULL' && filter.operator !== 'IS NOT NULL' && (
+            <Input
+              width={30}
+              placeholder={filter.operator === 'IN' || filter.operator === 'NOT IN' ? 'a, b, c' : 'value'}
+              defaultValue={formatFilterValue(filter.value)}
+              onBlur={(event) =>
+                update({
+                  filters: updateItem(filters, index, {
+                    value: parseFilterValue(filter.operator, event.currentTarget.value),
+                  }),
+                })
+              }
+            />
+          )}
+          <IconButton
+            name="trash-alt"
+            aria-label="Remove filter"
+            onClick={() => update({ filters: removeItem(filters, index) })}
+          />
+        </InlineFieldRow>
+      ))}
+      <InlineFieldRow>
+        <InlineField label="Group by" labelWidth={16}>
+          <MultiSelect
+            width={45}
+            allowCustomValue
+            options={columns}
+            value={(builder.groupBy || []).map((column) => toOption(column) as SelectableValue<string>)}
+            onChange={(options) => update({ groupBy: options.map((option) => option.value as string) })}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      {orders.map((order, index) => (
+        <InlineFieldRow key={`order-${index}-${orders.length}`}>
+          <InlineField label={index === 0 ? 'Order by' : ''} labelWidth={16}>
+            <Select
+              width={30}
+              allowCustomValue
+              options={columns}
+              value={toOption(order.column)}
+              onChange={(option) => update({ orderBy: updateItem(orders, index, { column: option?.value || '' }) })}
+            />
+          </InlineField>
+          <RadioButtonGroup
+            options={directionOptions}
+            value={order.direction || 'ASC'}
+            onChange={(direction) => update({ orderBy: updateItem(orders, index, { direction }) })}
+          />
+          <IconButton
+            name="trash-alt"
+            aria-label="Remove order"
+            onClick={() => update({ orderBy: removeItem(orders, index) })}
+          />
+        </InlineFieldRow>
+      ))}
+      <InlineFieldRow>
+        <InlineField label="Limit" labelWidth={16}>
+          <Input
+            width={15}
+            type="number"
+            min={0}
+            placeholder="none"
+            defaultValue={builder.limit}
+            onBlur={(event) => update({ limit: Number(event.currentTarget.value) || undefined })}
+          />
+        </InlineField>
+        <Button
+          variant="secondary"
+          icon="plus"
+          onClick={() => update({ columns: [...selectedColumns, { name: '' }] })}
+        >
+          Column
+        </Button>
+        <Button
+          variant="secondary"
+          icon="plus"
+          onClick={() => update({ filters: [...filters, { column: '', operator: '=', value: '' }] })}
+        >
+          Filter
+        </Button>
+        <Button variant="secondary" icon="plus" onClick={() => update({ orderBy: [...orders, { column: '' }] })}>
+          Order
+        </Button>
+      </InlineFieldRow>
+    </div>
+  );
+}
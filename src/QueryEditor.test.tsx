@@ -41,6 +41,47 @@ describe('QueryEditor', () => {
     });
   });
 
+  it('allows switching to the query builder', async () => {
+    render(queryEditor);
+
+    await userEvent.click(await screen.findByText('Builder'));
+
+    // the query is only executed after a table was selected
+    expect(onRunQueryMock).not.toHaveBeenCalled();
+    expect(onChangeMock).toHaveBeenLastCalledWith({
+      editorMode: 'builder',
+      builder: { table: '' },
+    });
+  });
+
+  it('shows the schema of the database', async () => {
+    const datasource = {
+      getTables: jest.fn().mockResolvedValue([{ name: 'measurements' }]),
+      getViews: jest.fn().mockResolvedValue([]),
+      getAllColumns: jest.fn().mockResolvedValue({
+        measurements: [{ name: 'value', type: 'REAL', notNull: false, primaryKey: false }],
+      }),
+      getIndexes: jest.fn().mockResolvedValue([
+        { name: 'measurements_value', table: 'measurements', unique: false, columns: ['value'] },
+      ]),
+    };
+    render(
+      <QueryEditor
+        onChange={onChangeMock}
+        onRunQuery={onRunQueryMock}
+        query={null as any}
+        datasource={datasource as any}
+      />
+    );
+
+    await userEvent.click(await screen.findByText('Schema'));
+    await userEvent.click(await screen.findByText('measurements'));
+
+    expect(await screen.findByText('REAL')).toBeInTheDocument();
+    expect(await screen.findByText('measurements_value', { exact: false })).toBeInTheDocument();
+    expect(datasource.getAllColumns).toHaveBeenCalledTimes(1);
+  });
+
   it('allows adding time columns', async () => {
     const { findByRole } = render(queryEditor);
 
@@ -1,13 +1,32 @@
 import { QueryEditorProps, SelectableValue } from '@grafana/data';
-import { Alert, CodeEditor, Icon, InlineFormLabel, Select, Switch, TagsInput, TextArea } from '@grafana/ui';
+import {
+  Alert,
+  Button,
+  CodeEditor,
+  Icon,
+  InlineFormLabel,
+  Input,
+  RadioButtonGroup,
+  Select,
+  Switch,
+  TagsInput,
+  TextArea,
+} from '@grafana/ui';
 import defaults from 'lodash/defaults';
-import React, { ChangeEvent, useState } from 'react';
+import React, { ChangeEvent, useCallback, useEffect, useState } from 'react';
 
 import { DataSource } from './DataSource';
-import { defaultQuery, MyDataSourceOptions, SQLiteQuery } from './types';
+import { isBuilderComplete, QueryBuilder } from './QueryBuilder';
+import { loadSchema, SchemaBrowser, schemaSuggestions, TableSchema } from './SchemaBrowser';
+import { BuilderQuery, defaultQuery, MyDataSourceOptions, SQLiteQuery } from './types';
 
 type Props = QueryEditorProps<DataSource, SQLiteQuery, MyDataSourceOptions>;
 
+const editorModeOptions: Array<SelectableValue<'code' | 'builder'>> = [
+  { label: 'Code', value: 'code' },
+  { label: 'Builder', value: 'builder' },
+];
+
 function calculateHeight(queryText: string): number {
   const minHeight = 200;
   const maxHeight = 500;
@@ -31,6 +50,37 @@ export function QueryEditor(props: Props) {
     props.onRunQuery();
   }
 
+  function onEdgesQueryTextChange(value: string) {
+    const { onChange, query } = props;
+    onChange({
+      ...query,
+      rawEdgesQueryText: value || undefined,
+      edgesQueryText: value || undefined,
+    });
+
+    props.onRunQuery();
+  }
+
+  function onEditorModeChange(editorMode: 'code' | 'builder') {
+    const { onChange, query } = props;
+    const builder = query.builder || { table: '' };
+    onChange({ ...query, editorMode, builder });
+
+    if (editorMode === 'code' || isBuilderComplete(builder)) {
+      props.onRunQuery();
+    }
+  }
+
+  // incomplete builder queries (e.g. a new filter without a column) are not executed
+  function onBuilderChange(builder: BuilderQuery) {
+    const { onChange, query } = props;
+    onChange({ ...query, builder });
+
+    if (isBuilderComplete(builder)) {
+      props.onRunQuery();
+    }
+  }
+
   function onQueryTypeChange(value: SelectableValue<string>) {
     const { onChange, query } = props;
     onChange({
@@ -51,20 +101,74 @@ export function QueryEditor(props: Props) {
     props.onRunQuery();
   }
 
+  function onStreamKeyColumnChange(value: string) {
+    const { onChange, query } = props;
+    onChange({
+      ...query,
+      streamKeyColumn: value || undefined,
+    });
+
+    props.onRunQuery();
+  }
+
   const query = defaults(props.query, defaultQuery);
-  const { rawQueryText, timeColumns } = query;
+  const { rawQueryText, timeColumns, boolColumns } = query;
   const [showHelp, setShowHelp] = useState(false);
   const [useLegacyEditor, setUseLegacyEditor] = useState(false);
+  const [showSchema, setShowSchema] = useState(false);
+  const [schema, setSchema] = useState<TableSchema[]>([]);
+
+  // the schema is the content of the schema browser and the suggestions of the code editor
+  useEffect(() => {
+    if (!props.datasource) {
+      return;
+    }
+    loadSchema(props.datasource)
+      .then(setSchema)
+      .catch(() => setSchema([]));
+  }, [props.datasource]);
+  const getSuggestions = useCallback(() => schemaSuggestions(schema), [schema]);
 
   const options: Array<SelectableValue<string>> = [
     { label: 'Table', value: 'table' },
     { label: 'Time series', value: 'time series' },
+    { label: 'Logs', value: 'logs' },
+    { label: 'Traces', value: 'traces' },
+    { label: 'Node graph', value: 'node graph' },
   ];
   const selectedOption = options.find((options) => options.value === query.queryType) || options[0];
 
   return (
     <>
-      {useLegacyEditor ? (
+      <div className="gf-form" role="editor-mode-container">
+        <RadioButtonGroup
+          size="sm"
+          options={editorModeOptions}
+          value={query.editorMode || 'code'}
+          onChange={onEditorModeChange}
+        />
+        {query.editorMode !== 'builder' && props.datasource && (
+          <Button
+            size="sm"
+            variant="secondary"
+            fill="text"
+            icon={showSchema ? 'angle-down' : 'angle-right'}
+            onClick={() => setShowSchema(!showSchema)}
+          >
+            Schema
+          </Button>
+        )}
+      </div>
+      {showSchema && query.editorMode !== 'builder' && props.datasource && (
+        <SchemaBrowser datasource={props.datasource} schema={schema} />
+      )}
+      {query.editorMode === 'builder' ? (
+        <QueryBuilder
+          datasource={props.datasource}
+          builder={query.builder || { table: '' }}
+          onChange={onBuilderChange}
+        />
+      ) : useLegacyEditor ? (
         <div className="gf-form">
           <TextArea
             style={{ height: 100 }}
@@ -82,8 +186,25 @@ export function QueryEditor(props: Props) {
           onSave={onQueryTextChange}
           language="sql"
           showMiniMap={false}
+          getSuggestions={getSuggestions}
         />
       )}
+      {query.queryType === 'node graph' && (
+        <>
+          <InlineFormLabel width={20} tooltip="Returns the edges (id, source and target). Without this query the nodes and edges are distinguished by a column named kind">
+            <div style={{ whiteSpace: 'nowrap' }}>Edges query (optional)</div>
+          </InlineFormLabel>
+          <CodeEditor
+            height={calculateHeight(query.rawEdgesQueryText || '')}
+            value={query.rawEdgesQueryText || ''}
+            onBlur={onEdgesQueryTextChange}
+            onSave={onEdgesQueryTextChange}
+            language="sql"
+            showMiniMap={false}
+            getSuggestions={getSuggestions}
+          />
+        </>
+      )}
       <div className="gf-form-inline">
         <div className="gf-form" role="query-type-container" style={{ marginRight: 15 }}>
           <InlineFormLabel>
@@ -106,6 +227,24 @@ export function QueryEditor(props: Props) {
             </InlineFormLabel>
             <TagsInput onChange={(tags: string[]) => onUpdateColumnTypes('timeColumns', tags)} tags={timeColumns} />
           </div>
+          <div style={{ display: 'flex', flexDirection: 'row', marginRight: 15 }} role="bool-column-selector">
+            <InlineFormLabel tooltip="Columns declared as BOOLEAN are detected automatically. Computed columns (e.g. comparisons) need to be listed">
+              <div style={{ whiteSpace: 'nowrap' }}>Boolean columns</div>
+            </InlineFormLabel>
+            <TagsInput onChange={(tags: string[]) => onUpdateColumnTypes('boolColumns', tags)} tags={boolColumns} />
+          </div>
+          <div className="gf-form" style={{ marginRight: 15 }}>
+            <InlineFormLabel tooltip="Streams new rows to the panel. New rows are detected by this incrementing column (e.g. an id or a timestamp)">
+              <div style={{ whiteSpace: 'nowrap' }}>Stream key column:</div>
+            </InlineFormLabel>
+            <Input
+              role="stream-key-column-input"
+              width={20}
+              placeholder="none"
+              defaultValue={query.streamKeyColumn}
+              onBlur={(event) => onStreamKeyColumnChange(event.currentTarget.value)}
+            />
+          </div>
           <div className="gf-form" style={{ alignItems: 'center' }}>
             <InlineFormLabel>
               <div style={{ whiteSpace: 'nowrap' }}>Use legacy code editor:</div>
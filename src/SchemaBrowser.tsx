@@ -0,0 +1,107 @@
+import { CodeEditorSuggestionItem, CodeEditorSuggestionItemKind, Icon } from '@grafana/ui';
+import React, { useEffect, useState } from 'react';
+
+import { DataSource } from './DataSource';
+import { SchemaColumn, SchemaIndex } from './types';
+
+export interface TableSchema {
+  name: string;
+  columns: SchemaColumn[];
+  // the definition of views
+  sql?: string;
+}
+
+// loadSchema returns the tables and views of the database with their columns. The columns of all
+// tables are loaded with a single request. Tables, whose columns cannot be loaded, are returned
+// without columns
+export async function loadSchema(datasource: DataSource): Promise<TableSchema[]> {
+  const [tables, views, columns] = await Promise.all([
+    datasource.getTables(),
+    datasource.getViews(),
+    datasource.getAllColumns().catch(() => ({} as Record<string, SchemaColumn[]>)),
+  ]);
+  return [...tables, ...views].map((table) => ({
+    name: table.name,
+    columns: columns[table.name] ?? [],
+    sql: 'sql' in table ? table.sql : undefined,
+  }));
+}
+
+// schemaSuggestions returns the tables and columns as suggestions of the code editor
+export function schemaSuggestions(schema: TableSchema[]): CodeEditorSuggestionItem[] {
+  const suggestions: CodeEditorSuggestionItem[] = [];
+  const columns = new Set<string>();
+
+  for (const table of schema) {
+    suggestions.push({
+      label: table.name,
+      kind: CodeEditorSuggestionItemKind.Property,
+      detail: table.sql ? 'view' : 'table',
+    });
+    for (const column of table.columns) {
+      if (!columns.has(column.name)) {
+        columns.add(column.name);
+        suggestions.push({ label: column.name, kind: CodeEditorSuggestionItemKind.Field, detail: column.type });
+      }
+    }
+  }
+
+  return suggestions;
+}
+
+interface Props {
+  datasource: DataSource;
+  schema: TableSchema[];
+}
+
+/**
+ * Lists the tables and views of the database. They are expanded by clicking on their name to show
+ * their columns and indexes.
+ */
+export function SchemaBrowser({ datasource, schema }: Props) {
+  const [indexes, setIndexes] = useState<SchemaIndex[]>([]);
+  const [expanded, setExpanded] = useState<string[]>([]);
+
+  useEffect(() => {
+    datasource
+      .getIndexes()
+      .then(setIndexes)
+      .catch(() => setIndexes([]));
+  }, [datasource]);
+
+  function toggle(table: string) {
+    setExpanded(expanded.includes(table) ? expanded.filter((name) => name !== table) : [...expanded, table]);
+  }
+
+  return (
+    <div role="schema-browser" style={{ maxHeight: 300, overflowY: 'auto', marginBottom: 8 }}>
+      {schema.length === 0 && <div>No tables found</div>}
+      {schema.map((table) => (
+        <div key={table.name}>
+          <div style={{ cursor: 'pointer' }} title={table.sql} onClick={() => toggle(table.name)}>
+            <Icon name={expanded.includes(table.name) ? 'angle-down' : 'angle-right'} />{' '}
+            <Icon name={table.sql ? 'eye' : 'table'} /> {table.name}
+          </div>
+          {expanded.includes(table.name) && (
+            <ul style={{ listStyle: 'none', paddingLeft: 32 }}>
+              {table.columns.map((column) => (
+                <li key={column.name}>
+                  {column.name} <code>{column.type || 'ANY'}</code>
+                  {column.primaryKey && ' (primary key)'}
+                  {column.notNull && ' NOT NULL'}
+                </li>
+              ))}
+              {indexes
+                .filter((index) => index.table === table.name)
+                .map((index) => (
+                  <li key={index.name}>
+                    <Icon name="bolt" /> {index.name} ({index.columns.join(', ')}){index.unique && ' UNIQUE'}
+                  </li>
+                ))}
+            </ul>
+          )}
+        </div>
+      ))}
+    </div>
+  );
+}
@@ -1,9 +1,77 @@
 import { DataQuery, DataSourceJsonData } from '@grafana/data';
 
+export type EpochUnit = 's' | 'ms' | 'us' | 'ns' | 'julianDay';
+
+export type ColumnType = 'time' | 'int' | 'float' | 'string' | 'bool' | 'json';
+
+export type BlobFormat = 'hex' | 'base64' | 'length' | 'utf8';
+
+export interface LogContextQuery {
+  // time of the log line in unix milliseconds
+  time: number;
+  direction: 'backward' | 'forward';
+  limit?: number;
+}
+
 export interface SQLiteQuery extends DataQuery {
   rawQueryText: string;
   queryText: string;
   timeColumns: string[];
+  // boolColumns are converted to booleans (columns declared as BOOLEAN are detected automatically)
+  boolColumns?: string[];
+  // columnTypes overrides the detected type of columns by name
+  columnTypes?: Record<string, ColumnType>;
+  // expandJsonColumns flattens JSON objects into one field per dotted path. The value is the depth
+  // of flattened nested objects (0 means no limit)
+  expandJsonColumns?: Record<string, number>;
+  // blobFormats sets the format of BLOB values by column name (utf8 by default)
+  blobFormats?: Record<string, BlobFormat>;
+  // timeFormat (a Go layout) and epochUnit override the time parsing settings of the datasource
+  timeFormat?: string;
+  epochUnit?: EpochUnit;
+  queryTimeout?: number;
+  maxRows?: number;
+  // streamKeyColumn enables live streaming. New rows are detected by this incrementing column
+  streamKeyColumn?: string;
+  // streamInterval is the polling interval of the stream in milliseconds
+  streamInterval?: number;
+  // edgesQueryText is the query of the edges of a node graph query (optional)
+  rawEdgesQueryText?: string;
+  edgesQueryText?: string;
+  // logContext selects the log lines before or after a log line of a logs query
+  logContext?: LogContextQuery;
+  // in the builder mode the backend compiles the query from the builder model instead of the query text
+  editorMode?: 'code' | 'builder';
+  builder?: BuilderQuery;
+}
+
+export type BuilderAggregation = 'count' | 'sum' | 'avg' | 'min' | 'max';
+export type BuilderOperator =
+  | '='
+  | '!='
+  | '<'
+  | '<='
+  | '>'
+  | '>='
+  | 'LIKE'
+  | 'NOT LIKE'
+  | 'IN'
+  | 'NOT IN'
+  | 'IS NULL'
+  | 'IS NOT NULL';
+
+/**
+ * The structured query of the visual query builder.
+ */
+export interface BuilderQuery {
+  table: string;
+  columns?: Array<{ name: string; aggregation?: BuilderAggregation; alias?: string }>;
+  filters?: Array<{ column: string; operator: BuilderOperator; value?: string | number | boolean | null | Array<string | number> }>;
+  groupBy?: string[];
+  orderBy?: Array<{ column: string; direction?: 'ASC' | 'DESC' }>;
+  limit?: number;
+  timeColumn?: string;
+  timeFormat?: 'iso' | 'unix' | 'unixMs';
 }
 
 export const defaultQuery: Partial<SQLiteQuery> = {
@@ -28,7 +96,42 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
   pathPrefix?: string;
   pathOptions?: string;
   attachLimit?: number;
+  maxConcurrentQueries?: number;
+  queryTimeout?: number;
+  maxRows?: number;
+  // maxBlobSize is the maximum number of bytes of BLOB values (1 MiB by default)
+  maxBlobSize?: number;
+  timeFormat?: string;
+  epochUnit?: EpochUnit;
+  // timezone (an IANA name) of time strings without an offset
+  timezone?: string;
 }
 export interface MySecureJsonData {
   securePathOptions?: string;
 }
+
+/**
+ * The schema of the database as returned by the resource endpoints of the backend.
+ */
+export interface SchemaTable {
+  name: string;
+}
+
+export interface SchemaColumn {
+  name: string;
+  type: string;
+  notNull: boolean;
+  primaryKey: boolean;
+}
+
+export interface SchemaIndex {
+  name: string;
+  table: string;
+  unique: boolean;
+  columns: string[];
+}
+
+export interface SchemaView {
+  name: string;
+  sql: string;
+}